@@ -0,0 +1,98 @@
+package crypt
+
+import (
+	"crypto/cipher"
+	"fmt"
+
+	ciphers "github.com/kayon/crypt/cipher"
+)
+
+// aeadNonceSize returns the default, minimum and maximum nonce size of an AES
+// AEAD mode.
+func aeadNonceSize(mode BlockMode) (size, min, max int) {
+	switch mode {
+	case MODE_CCM:
+		return 12, 7, 13
+	case MODE_EAX:
+		return 16, 1, 16
+	case MODE_OCB:
+		return 12, 1, 15
+	}
+	return gcmStandardNonceSize, gcmStandardNonceSize, gcmStandardNonceSize
+}
+
+// aeadTagSize returns the default, minimum and maximum tag size of an AES AEAD
+// mode. CCM tags must also be even.
+func aeadTagSize(mode BlockMode) (size, min, max int) {
+	switch mode {
+	case MODE_GCM:
+		return 16, 12, 16
+	case MODE_CCM, MODE_EAX, MODE_OCB:
+		return 16, 4, 16
+	}
+	return 16, 16, 16
+}
+
+// aeadSizes validates Options.NonceSize and Options.TagSize for an AES AEAD
+// mode. Without Options.NonceSize the nonce size of a variable length mode is
+// that of iv, if given.
+func aeadSizes(mode BlockMode, iv []byte, opts Options) (nonceSize, tagSize int, err error) {
+	size, min, max := aeadNonceSize(mode)
+	if nonceSize = opts.NonceSize; nonceSize == 0 {
+		if nonceSize = size; iv != nil && min != max {
+			nonceSize = len(iv)
+		}
+	}
+	if nonceSize < min || nonceSize > max {
+		if min == max {
+			return 0, 0, nonceSizeError(METHOD_AES, nonceSize, min)
+		}
+		return 0, 0, nonceRangeError(METHOD_AES, nonceSize, min, max)
+	} else if iv != nil && len(iv) != nonceSize {
+		return 0, 0, nonceSizeError(METHOD_AES, len(iv), nonceSize)
+	}
+	size, min, max = aeadTagSize(mode)
+	if tagSize = opts.TagSize; tagSize == 0 {
+		tagSize = size
+	}
+	if tagSize < min || tagSize > max || (mode == MODE_CCM && tagSize%2 != 0) {
+		return 0, 0, fmt.Errorf("crypt AES: invalid %s tag size %d", mode, tagSize)
+	}
+	return nonceSize, tagSize, nil
+}
+
+// newAEAD returns the AES AEAD of mode. GCM-SIV derives its keys from key,
+// the other modes use block.
+func newAEAD(mode BlockMode, key []byte, block cipher.Block, nonceSize, tagSize int) (cipher.AEAD, error) {
+	switch mode {
+	case MODE_GCM:
+		if tagSize == 16 {
+			return cipher.NewGCM(block)
+		}
+		return cipher.NewGCMWithTagSize(block, tagSize)
+	case MODE_CCM:
+		return ciphers.NewCCM(block, nonceSize, tagSize)
+	case MODE_EAX:
+		return ciphers.NewEAX(block, nonceSize, tagSize)
+	case MODE_OCB:
+		return ciphers.NewOCB(block, nonceSize, tagSize)
+	case MODE_GCMSIV:
+		return ciphers.NewGCMSIV(key)
+	}
+	return nil, fmt.Errorf("crypt AES: %s is not an AEAD mode", mode)
+}
+
+// aeadMaxLength returns the largest plaintext the AEAD of mode can seal.
+func aeadMaxLength(mode BlockMode, nonceSize int) uint64 {
+	switch mode {
+	case MODE_GCM:
+		return ((1 << 32) - 2) * 16
+	case MODE_CCM:
+		if l := 15 - nonceSize; l < 8 {
+			return 1<<(8*uint(l)) - 1
+		}
+	case MODE_GCMSIV:
+		return 1 << 36
+	}
+	return 1<<64 - 1
+}
@@ -0,0 +1,181 @@
+package crypt
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/hex"
+	"errors"
+	"io"
+	"testing"
+
+	ciphers "github.com/kayon/crypt/cipher"
+)
+
+func TestAEADVectors(t *testing.T) {
+	var vectors = []struct {
+		name                           string
+		key, nonce, aad, plaintext, ct string
+		tagSize                        int
+	}{
+		// RFC 3610 packet vector #1, NIST SP 800-38C example 1
+		{"CCM", "c0c1c2c3c4c5c6c7c8c9cacbcccdcecf", "00000003020100a0a1a2a3a4a5", "0001020304050607", "08090a0b0c0d0e0f101112131415161718191a1b1c1d1e", "588c979a61c663d2f066d0c2c0f989806d5f6b61dac38417e8d12cfdf926e0", 8},
+		{"CCM", "404142434445464748494a4b4c4d4e4f", "10111213141516", "0001020304050607", "20212223", "7162015b4dac255d", 4},
+		// EAX paper appendix
+		{"EAX", "233952dee4d5ed5f9b9c6d6ff80ff478", "62ec67f9c3a4a407fcb2a8c49031a8b3", "6bfb914fd07eae6b", "", "e037830e8389f27b025a2d6527e79d01", 16},
+		{"EAX", "91945d3f4dcbee0bf45ef52255f095a4", "becaf043b0a23d843194ba972c66debd", "fa3bfd4806eb53fa", "f7fb", "19dd5c4c9331049d0bdab0277408f67967e5", 16},
+		{"EAX", "01f74ad64077f2e704c0f60ada3dd523", "70c3db4f0d26368400a10ed05d2bff5e", "234a3463c1264ac6", "1a47cb4933", "d851d5bae03a59f238a23e39199dc9266626c40f80", 16},
+		// RFC 7253 appendix A
+		{"OCB", "000102030405060708090a0b0c0d0e0f", "bbaa99887766554433221100", "", "", "785407bfffc8ad9edcc5520ac9111ee6", 16},
+		{"OCB", "000102030405060708090a0b0c0d0e0f", "bbaa99887766554433221101", "0001020304050607", "0001020304050607", "6820b3657b6f615a5725bda0d3b4eb3a257c9af1f8f03009", 16},
+		{"OCB", "000102030405060708090a0b0c0d0e0f", "bbaa99887766554433221102", "0001020304050607", "", "81017f8203f081277152fade694a0a00", 16},
+		{"OCB", "000102030405060708090a0b0c0d0e0f", "bbaa99887766554433221103", "", "0001020304050607", "45dd69f8f5aae72414054cd1f35d82760b2cd00d2f99bfa9", 16},
+		{"OCB", "000102030405060708090a0b0c0d0e0f", "bbaa99887766554433221104", "000102030405060708090a0b0c0d0e0f", "000102030405060708090a0b0c0d0e0f", "571d535b60b277188be5147170a9a22c3ad7a4ff3835b8c5701c1ccec8fc3358", 16},
+		{"OCB", "0f0e0d0c0b0a09080706050403020100", "bbaa9988776655443322110d", "000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f2021222324252627", "000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f2021222324252627", "1792a4e31e0755fb03e31b22116e6c2ddf9efd6e33d536f1a0124b0a55bae884ed93481529c76b6ad0c515f4d1cdd4fdac4f02aa", 12},
+		// RFC 8452 appendix C.1 and C.2
+		{"GCM-SIV", "01000000000000000000000000000000", "030000000000000000000000", "", "", "dc20e2d83f25705bb49e439eca56de25", 16},
+		{"GCM-SIV", "01000000000000000000000000000000", "030000000000000000000000", "", "0100000000000000", "b5d839330ac7b786578782fff6013b815b287c22493a364c", 16},
+		{"GCM-SIV", "01000000000000000000000000000000", "030000000000000000000000", "01", "0200000000000000", "1e6daba35669f4273b0a1a2560969cdf790d99759abd1508", 16},
+		{"GCM-SIV", "0100000000000000000000000000000000000000000000000000000000000000", "030000000000000000000000", "", "", "07f5f4169bbf55a8400cd47ea6fd400f", 16},
+		{"GCM-SIV", "0100000000000000000000000000000000000000000000000000000000000000", "030000000000000000000000", "", "0100000000000000", "c2ef328e5c71c83b843122130f7364b761e0b97427e3df28", 16},
+	}
+	for i, v := range vectors {
+		key, _ := hex.DecodeString(v.key)
+		nonce, _ := hex.DecodeString(v.nonce)
+		aad, _ := hex.DecodeString(v.aad)
+		plaintext, _ := hex.DecodeString(v.plaintext)
+		block, _ := aes.NewCipher(key)
+		var aead cipher.AEAD
+		var err error
+		switch v.name {
+		case "CCM":
+			aead, err = ciphers.NewCCM(block, len(nonce), v.tagSize)
+		case "EAX":
+			aead, err = ciphers.NewEAX(block, len(nonce), v.tagSize)
+		case "OCB":
+			aead, err = ciphers.NewOCB(block, len(nonce), v.tagSize)
+		case "GCM-SIV":
+			aead, err = ciphers.NewGCMSIV(key)
+		}
+		if err != nil {
+			t.Fatal(i, v.name, err)
+		}
+		ciphertext := aead.Seal(nil, nonce, plaintext, aad)
+		if hex.EncodeToString(ciphertext) != v.ct {
+			t.Errorf("%d %s: Seal %x, want %s", i, v.name, ciphertext, v.ct)
+			continue
+		}
+		opened, err := aead.Open(nil, nonce, ciphertext, aad)
+		if err != nil || hex.EncodeToString(opened) != v.plaintext {
+			t.Errorf("%d %s: Open %x, %v", i, v.name, opened, err)
+		}
+		ciphertext[0] ^= 1
+		if _, err = aead.Open(nil, nonce, ciphertext, aad); err == nil {
+			t.Errorf("%d %s: Open accepted a modified ciphertext", i, v.name)
+		}
+	}
+}
+
+func TestAEADModes(t *testing.T) {
+	var text = []byte("Sphinx of black quartz, judge my vow")
+	var key = []byte("15234c27ef5da06b15234c27ef5da06b")
+	var aad = []byte("header")
+	for _, mode := range []BlockMode{MODE_GCM, MODE_CCM, MODE_EAX, MODE_OCB, MODE_GCMSIV} {
+		var crypts = map[string]func() (*Crypt, error){
+			"nonce":    func() (*Crypt, error) { return NewAES(key, randBytes(12), Options{Mode: mode, AAD: aad}) },
+			"password": func() (*Crypt, error) { return NewAES(testPassword, nil, Options{Mode: mode, AAD: aad}) },
+		}
+		if mode.Has(MODE_CCM, MODE_EAX, MODE_OCB) {
+			crypts["sizes"] = func() (*Crypt, error) {
+				return NewAES(key, nil, Options{Mode: mode, NonceSize: 8, TagSize: 8, AAD: aad, KDF: Scrypt{N: 1 << 10}})
+			}
+		}
+		for name, fn := range crypts {
+			c, err := fn()
+			if err != nil {
+				t.Fatal(mode, name, err)
+			}
+			ciphertext, err := c.Encrypt(text)
+			if err != nil {
+				t.Fatal(mode, name, err)
+			}
+			plaintext, err := c.Decrypt(ciphertext)
+			if err != nil || !bytes.Equal(plaintext, text) {
+				t.Fatalf("%s %s: Decrypt %q, %v", mode, name, plaintext, err)
+			}
+			ciphertext[len(ciphertext)-1] ^= 1
+			if _, err = c.Decrypt(ciphertext); !errors.Is(err, ErrAuthentication) {
+				t.Fatalf("%s %s: modified ciphertext: %v", mode, name, err)
+			}
+
+			var buf bytes.Buffer
+			w, err := c.NewEncryptWriter(&buf)
+			if err != nil {
+				t.Fatal(mode, name, err)
+			}
+			w.Write(text)
+			if err = w.Close(); err != nil {
+				t.Fatal(mode, name, err)
+			}
+			r, err := c.NewDecryptReader(&buf)
+			if err != nil {
+				t.Fatal(mode, name, err)
+			}
+			if plaintext, err = io.ReadAll(r); err != nil || !bytes.Equal(plaintext, text) {
+				t.Fatalf("%s %s: stream %q, %v", mode, name, plaintext, err)
+			}
+
+			envelope, err := c.EncryptEnvelope(text)
+			if err != nil {
+				t.Fatal(mode, name, err)
+			}
+			password := testPassword
+			if name != "password" {
+				password = key
+			}
+			if plaintext, err = OpenWithAAD(envelope, password, aad); err != nil || !bytes.Equal(plaintext, text) {
+				t.Fatalf("%s %s: envelope %q, %v", mode, name, plaintext, err)
+			}
+		}
+	}
+
+	// the nonce size of the variable length modes follows the given nonce
+	nonce, _ := hex.DecodeString("00000003020100a0a1a2a3a4a5")
+	ciphertext, err := AES.Encrypt(text, key[:16], nonce, Options{Mode: MODE_CCM, TagSize: 8})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ciphertext) != len(text)+8 {
+		t.Fatalf("CCM: ciphertext length %d", len(ciphertext))
+	}
+
+	var invalid = []struct {
+		iv   []byte
+		opts Options
+	}{
+		{randBytes(16), Options{Mode: MODE_GCM}},
+		{randBytes(14), Options{Mode: MODE_CCM}},
+		{randBytes(12), Options{Mode: MODE_CCM, NonceSize: 13}},
+		{nil, Options{Mode: MODE_OCB, NonceSize: 16}},
+		{randBytes(16), Options{Mode: MODE_GCMSIV}},
+	}
+	for _, v := range invalid {
+		if _, err = NewAES(key, v.iv, v.opts); !errors.Is(err, ErrInvalidNonce) {
+			t.Errorf("%s nonce %d/%d: %v", v.opts.Mode, len(v.iv), v.opts.NonceSize, err)
+		}
+	}
+	for _, opts := range []Options{
+		{Mode: MODE_GCM, TagSize: 8},
+		{Mode: MODE_CCM, TagSize: 5},
+		{Mode: MODE_GCMSIV, TagSize: 12},
+		{Mode: MODE_CBC, TagSize: 16},
+		{Mode: MODE_CTR, NonceSize: 12},
+	} {
+		if _, err = NewAES(key, nil, opts); err == nil {
+			t.Errorf("%s tag %d nonce %d: expected an error", opts.Mode, opts.TagSize, opts.NonceSize)
+		}
+	}
+	if _, err = NewDES3(testPassword, nil, Options{Mode: MODE_CCM}); err == nil {
+		t.Error("DES3 accepted MODE_CCM")
+	}
+}
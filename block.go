@@ -0,0 +1,247 @@
+package crypt
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/des"
+	"fmt"
+	"sort"
+	"sync"
+
+	ciphers "github.com/kayon/crypt/cipher"
+)
+
+// methodRegistered is the first CipherMethod RegisterBlockCipher accepts, the
+// lower ones are kept for the built-in methods.
+const methodRegistered CipherMethod = 128
+
+// blockCipher is a block cipher run by the generic engine, blockEncrypt and
+// blockDecrypt, in every BlockMode that is not an AEAD mode.
+type blockCipher struct {
+	name string
+	// keySizes are the valid key sizes, largest first
+	keySizes []int
+	// saltKeySize is the size of the key derived from a password
+	saltKeySize int
+	newCipher   func(key []byte) (cipher.Block, error)
+}
+
+var (
+	blockCiphersMu sync.RWMutex
+	blockCiphers   = map[CipherMethod]*blockCipher{
+		METHOD_AES:      {name: "AES", keySizes: []int{32, 24, 16}, saltKeySize: aesSaltKeyByteSize, newCipher: aes.NewCipher},
+		METHOD_DES:      {name: "DES", keySizes: []int{8}, saltKeySize: desSaltKeyByteSize, newCipher: des.NewCipher},
+		METHOD_DES3:     {name: "DES3", keySizes: []int{24}, saltKeySize: tripleDesSaltKeyByteSize, newCipher: des.NewTripleDESCipher},
+		METHOD_BLOWFISH: {name: "Blowfish", keySizes: []int{56}, saltKeySize: blowfishSaltKeyByteSize, newCipher: newBlowfish},
+		METHOD_TWOFISH:  {name: "Twofish", keySizes: []int{32, 24, 16}, saltKeySize: twofishSaltKeyByteSize, newCipher: newTwofish},
+		METHOD_CAST5:    {name: "CAST5", keySizes: []int{16}, saltKeySize: cast5SaltKeyByteSize, newCipher: newCAST5},
+		METHOD_CAMELLIA: {name: "Camellia", keySizes: []int{32, 24, 16}, saltKeySize: camelliaSaltKeyByteSize, newCipher: ciphers.NewCamellia},
+		METHOD_SM4:      {name: "SM4", keySizes: []int{16}, saltKeySize: sm4SaltKeyByteSize, newCipher: ciphers.NewSM4},
+		METHOD_SERPENT:  {name: "Serpent", keySizes: []int{32, 24, 16}, saltKeySize: serpentSaltKeyByteSize, newCipher: ciphers.NewSerpent},
+		METHOD_XTEA:     {name: "XTEA", keySizes: []int{16}, saltKeySize: xteaSaltKeyByteSize, newCipher: newXTEA},
+	}
+)
+
+// RegisterBlockCipher adds a block cipher as method, to be passed to New and
+// NewEnvelopeCrypt, that takes keys of keySizes bytes. It supports the same
+// modes, paddings and password header as DES.
+//
+// method is chosen by the caller from 128 to 255 and written into envelopes,
+// so it must stay the same for a cipher in every program sharing them. A
+// method or name that is already taken is an error.
+func RegisterBlockCipher(method CipherMethod, name string, keySizes []int, ctor func(key []byte) (cipher.Block, error)) error {
+	if method < methodRegistered {
+		return fmt.Errorf("crypt RegisterBlockCipher: method %d is reserved, use 128 to 255", method)
+	} else if name == "" || len(keySizes) == 0 || ctor == nil {
+		return fmt.Errorf("crypt RegisterBlockCipher: name, key sizes and constructor are required")
+	}
+	var sizes = make([]int, len(keySizes))
+	for i, n := range keySizes {
+		if n <= 0 {
+			return fmt.Errorf("crypt RegisterBlockCipher: %s: invalid key size %d", name, n)
+		}
+		sizes[i] = n
+	}
+	// verifyKey expects the largest size first
+	sort.Sort(sort.Reverse(sort.IntSlice(sizes)))
+	for m := CipherMethod(0); m < methodRegistered; m++ {
+		if m.String() == name {
+			return fmt.Errorf("crypt RegisterBlockCipher: %s is a built-in method", name)
+		}
+	}
+
+	blockCiphersMu.Lock()
+	defer blockCiphersMu.Unlock()
+	if b := blockCiphers[method]; b != nil {
+		return fmt.Errorf("crypt RegisterBlockCipher: method %d is already registered as %s", method, b.name)
+	}
+	for _, b := range blockCiphers {
+		if b.name == name {
+			return fmt.Errorf("crypt RegisterBlockCipher: %s is already registered", name)
+		}
+	}
+	blockCiphers[method] = &blockCipher{name: name, keySizes: sizes, saltKeySize: sizes[0], newCipher: ctor}
+	return nil
+}
+
+// blockCipherOf returns the block cipher of method, nil if it is not one.
+func blockCipherOf(method CipherMethod) *blockCipher {
+	blockCiphersMu.RLock()
+	defer blockCiphersMu.RUnlock()
+	return blockCiphers[method]
+}
+
+// blockEncrypt encrypts src with the block cipher of c. Without an IV the key
+// and IV are derived from a salted header written in front of the ciphertext.
+func (c Crypt) blockEncrypt(src, aad []byte) (ciphertext []byte, err error) {
+	switch {
+	case c.mode == MODE_SIV:
+		return sivEncrypt(src, c.key, c.iv, sivComponents(aad))
+	case c.mode == MODE_XTS:
+		return c.xts(src, true)
+	case c.legacy:
+		return blowfishLegacyEncrypt(src, c.block)
+	}
+	var header, plaintext []byte
+	var offset int
+	var key, iv, block = c.key, c.iv, c.block
+	if c.mode.padded() {
+		plaintext, err = Padding(c.padding, src, block.BlockSize())
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		plaintext = append([]byte{}, src...)
+	}
+	if c.salted() {
+		if header, key, iv, err = genHeader(c.kdf, key, c.password, c.ivSize(), c.saltKeyByteSize()); err != nil {
+			return nil, err
+		}
+		if block, err = newBlockCipher(c.method, key); err != nil {
+			return nil, err
+		}
+		offset = len(header)
+		ciphertext = append(header, plaintext...)
+	} else {
+		ciphertext = append([]byte{}, plaintext...)
+	}
+
+	switch c.mode {
+	case MODE_CBC:
+		bm := cipher.NewCBCEncrypter(block, iv)
+		bm.CryptBlocks(ciphertext[offset:], plaintext)
+	case MODE_CFB:
+		stream := cipher.NewCFBEncrypter(block, iv)
+		stream.XORKeyStream(ciphertext[offset:], plaintext)
+	case MODE_CTR:
+		stream := cipher.NewCTR(block, iv)
+		stream.XORKeyStream(ciphertext[offset:], plaintext)
+	case MODE_OFB:
+		stream := cipher.NewOFB(block, iv)
+		stream.XORKeyStream(ciphertext[offset:], plaintext)
+	case MODE_ECB:
+		bm := ciphers.NewECBEncrypter(block)
+		bm.CryptBlocks(ciphertext[offset:], plaintext)
+	case MODE_IGE:
+		bm := ciphers.NewIGEEncrypter(block, iv)
+		bm.CryptBlocks(ciphertext[offset:], plaintext)
+	case MODE_PCBC:
+		bm := ciphers.NewPCBCEncrypter(block, iv)
+		bm.CryptBlocks(ciphertext[offset:], plaintext)
+	case MODE_CBC_CTS:
+		bm, err := newCTS(c.method, block, iv, c.cts, plaintext, true)
+		if err != nil {
+			return nil, err
+		}
+		bm.CryptBlocks(ciphertext[offset:], plaintext)
+	case MODE_GCM, MODE_CCM, MODE_EAX, MODE_OCB, MODE_GCMSIV:
+		if uint64(len(plaintext)) > aeadMaxLength(c.mode, len(iv)) {
+			return nil, fmt.Errorf("crypt %s.Encrypt: plaintext too large for %s", c.method, c.mode)
+		}
+		aead, err := newAEAD(c.mode, key, block, len(iv), c.tagSize)
+		if err != nil {
+			return nil, err
+		}
+		ciphertext = append(ciphertext[:offset], aead.Seal(nil, iv, plaintext, aad)...)
+	}
+	return
+}
+
+// blockDecrypt decrypts src with the block cipher of c, reading the salted
+// header src starts with, if any.
+func (c Crypt) blockDecrypt(src, aad []byte) (plaintext []byte, err error) {
+	switch {
+	case c.mode == MODE_SIV:
+		return sivDecrypt(src, c.key, c.iv, sivComponents(aad))
+	case c.mode == MODE_XTS:
+		return c.xts(src, false)
+	case c.legacy:
+		return blowfishLegacyDecrypt(src, c.block)
+	}
+	var ciphertext []byte
+	var offset int
+	var key, iv, block = c.key, c.iv, c.block
+	var derivedKey, derivedIV []byte
+	var ivSize = c.ivSize()
+	if offset, derivedKey, derivedIV, err = parseHeader(src, key, c.password, ivSize, c.saltKeyByteSize()); err != nil {
+		return nil, err
+	} else if offset > 0 {
+		key, iv = derivedKey, derivedIV
+		if block, err = newBlockCipher(c.method, key); err != nil {
+			return nil, err
+		}
+		ciphertext = append([]byte{}, src[offset:]...)
+	} else {
+		ciphertext = append([]byte{}, src...)
+	}
+	if c.mode.Not(MODE_ECB) && len(iv) != ivSize {
+		return nil, nonceSizeError(c.method, len(iv), ivSize)
+	}
+	if c.mode.padded() && len(ciphertext)%block.BlockSize() != 0 {
+		return nil, ErrInvalidPadding
+	}
+	plaintext = make([]byte, len(ciphertext))
+
+	switch c.mode {
+	case MODE_CBC:
+		bm := cipher.NewCBCDecrypter(block, iv)
+		bm.CryptBlocks(plaintext, ciphertext)
+	case MODE_CFB:
+		stream := cipher.NewCFBDecrypter(block, iv)
+		stream.XORKeyStream(plaintext, ciphertext)
+	case MODE_CTR:
+		stream := cipher.NewCTR(block, iv)
+		stream.XORKeyStream(plaintext, ciphertext)
+	case MODE_OFB:
+		stream := cipher.NewOFB(block, iv)
+		stream.XORKeyStream(plaintext, ciphertext)
+	case MODE_ECB:
+		bm := ciphers.NewECBDecrypter(block)
+		bm.CryptBlocks(plaintext, ciphertext)
+	case MODE_IGE:
+		bm := ciphers.NewIGEDecrypter(block, iv)
+		bm.CryptBlocks(plaintext, ciphertext)
+	case MODE_PCBC:
+		bm := ciphers.NewPCBCDecrypter(block, iv)
+		bm.CryptBlocks(plaintext, ciphertext)
+	case MODE_CBC_CTS:
+		var bm cipher.BlockMode
+		if bm, err = newCTS(c.method, block, iv, c.cts, ciphertext, false); err != nil {
+			return nil, err
+		}
+		bm.CryptBlocks(plaintext, ciphertext)
+	case MODE_GCM, MODE_CCM, MODE_EAX, MODE_OCB, MODE_GCMSIV:
+		var aead cipher.AEAD
+		if aead, err = newAEAD(c.mode, key, block, ivSize, c.tagSize); err != nil {
+			return nil, err
+		}
+		plaintext, err = aead.Open(nil, iv, ciphertext, aad)
+		if err != nil {
+			err = fmt.Errorf("crypt %s.Decrypt: %s %w", c.method, c.mode, ErrAuthentication)
+		}
+	}
+	if c.mode.padded() {
+		plaintext, err = UnPadding(c.padding, plaintext, block.BlockSize())
+	}
+	return
+}
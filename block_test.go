@@ -0,0 +1,99 @@
+package crypt
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"errors"
+	"testing"
+)
+
+// AES-128 registered again under another name, so the engine can be checked
+// against the built-in AES
+const registeredMethod CipherMethod = 200
+
+var registeredErr = RegisterBlockCipher(registeredMethod, "AES-128-registered", []int{16}, func(key []byte) (cipher.Block, error) {
+	return aes.NewCipher(key)
+})
+
+func TestRegisterBlockCipher(t *testing.T) {
+	if registeredErr != nil {
+		t.Fatal(registeredErr)
+	}
+	if registeredMethod.String() != "AES-128-registered" {
+		t.Fatalf("unexpected name %q", registeredMethod)
+	}
+	if err := RegisterBlockCipher(registeredMethod, "other", []int{16}, aes.NewCipher); err == nil {
+		t.Fatal("expected an error for a method already registered")
+	}
+	if err := RegisterBlockCipher(METHOD_XSALSA20+1, "reserved", []int{16}, aes.NewCipher); err == nil {
+		t.Fatal("expected an error for a reserved method")
+	}
+	var key, iv = randBytes(16), randBytes(16)
+	var text = []byte("registered block cipher")
+	for _, mode := range []BlockMode{MODE_CBC, MODE_CFB, MODE_CTR, MODE_OFB, MODE_ECB, MODE_CBC_CTS, MODE_IGE, MODE_PCBC} {
+		var modeIV = iv
+		if mode == MODE_IGE {
+			modeIV = append(append([]byte{}, iv...), iv...)
+		}
+		c, err := New(registeredMethod, key, modeIV, Options{Mode: mode})
+		if err != nil {
+			t.Fatal(mode, err)
+		}
+		ciphertext, err := c.Encrypt(text)
+		if err != nil {
+			t.Fatal(mode, err)
+		}
+		want, err := AES.Encrypt(text, key, modeIV, Options{Mode: mode})
+		if err != nil {
+			t.Fatal(mode, err)
+		}
+		if !bytes.Equal(ciphertext, want) {
+			t.Fatalf("%s: ciphertext differs from AES", mode)
+		}
+		if plaintext, err := c.Decrypt(ciphertext); err != nil || !bytes.Equal(plaintext, text) {
+			t.Fatal(mode, "decrypt failed", err)
+		}
+	}
+
+	// password, stream and envelope
+	c, err := New(registeredMethod, testPassword, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	w, err := c.NewEncryptWriter(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write(text)
+	w.Close()
+	if plaintext, err := c.Decrypt(buf.Bytes()); err != nil || !bytes.Equal(plaintext, text) {
+		t.Fatal("stream decrypt failed", err)
+	}
+	m, _ := New(registeredMethod, testPassword, nil, Options{MAC: MAC_HMAC_SHA256})
+	envelope, err := m.EncryptEnvelope(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plaintext, err := Open(envelope, testPassword); err != nil || !bytes.Equal(plaintext, text) {
+		t.Fatal("envelope open failed", err)
+	}
+
+	var keySizeErr *KeySizeError
+	if _, err = New(registeredMethod, randBytes(8), iv); !errors.As(err, &keySizeErr) {
+		t.Fatal("expected a KeySizeError", err)
+	}
+	if _, err = New(registeredMethod, key, nil, Options{Mode: MODE_GCM}); err == nil {
+		t.Fatal("expected an error for an AEAD mode")
+	}
+	if err = RegisterBlockCipher(registeredMethod+1, "AES-128-registered", []int{16}, aes.NewCipher); err == nil {
+		t.Fatal("expected an error for a duplicate name")
+	}
+	if err = RegisterBlockCipher(registeredMethod+1, "RC4", []int{16}, aes.NewCipher); err == nil {
+		t.Fatal("expected an error for a built-in name")
+	}
+	if err = RegisterBlockCipher(registeredMethod+1, "no key sizes", nil, aes.NewCipher); err == nil {
+		t.Fatal("expected an error without key sizes")
+	}
+}
@@ -2,36 +2,47 @@ package crypt
 
 import (
 	"crypto/cipher"
-	"fmt"
+
+	"golang.org/x/crypto/blowfish"
 )
 
-const blowfishBlockSize = 8
+const (
+	blowfishBlockSize = 8
+	// the default key size of OpenSSL bf-cbc
+	blowfishSaltKeyByteSize = 16
+)
 
 var Blowfish cryptBlowfish
 
 type cryptBlowfish struct{}
 
-func (cryptBlowfish) Encrypt(plaintext, key []byte) ([]byte, error) {
-	c, err := NewBlowfish(key)
+func (cryptBlowfish) Encrypt(plaintext, key, iv []byte, args ...Options) ([]byte, error) {
+	c, err := NewBlowfish(key, iv, args...)
 	if err != nil {
 		return nil, err
 	}
 	return c.Encrypt(plaintext)
 }
 
-func (cryptBlowfish) Decrypt(ciphertext, key []byte) ([]byte, error) {
-	c, err := NewBlowfish(key)
+func (cryptBlowfish) Decrypt(ciphertext, key, iv []byte, args ...Options) ([]byte, error) {
+	c, err := NewBlowfish(key, iv, args...)
 	if err != nil {
 		return nil, err
 	}
 	return c.Decrypt(ciphertext)
 }
 
-func blowfishEncrypt(src []byte, block cipher.Block) (ciphertext []byte, err error) {
+func newBlowfish(key []byte) (cipher.Block, error) {
+	return blowfish.NewCipher(key)
+}
+
+// blowfishLegacyEncrypt is the format of Options.Legacy: ECB, zero padded only
+// if the plaintext does not end on a block boundary.
+func blowfishLegacyEncrypt(src []byte, block cipher.Block) (ciphertext []byte, err error) {
 	var plaintext []byte
 	var b = make([]byte, blowfishBlockSize)
 	var size int
-	if len(src) % blowfishBlockSize != 0 {
+	if len(src)%blowfishBlockSize != 0 {
 		if plaintext, err = Padding(PAD_ZEROPADDING, src, blowfishBlockSize); err != nil {
 			return nil, err
 		}
@@ -39,7 +50,7 @@ func blowfishEncrypt(src []byte, block cipher.Block) (ciphertext []byte, err err
 		plaintext = append([]byte{}, src...)
 	}
 	size = len(plaintext) / blowfishBlockSize
-	ciphertext = make([]byte, 0, size * blowfishBlockSize)
+	ciphertext = make([]byte, 0, size*blowfishBlockSize)
 	for i := 0; i < size; i++ {
 		block.Encrypt(b, plaintext[i*blowfishBlockSize:(i+1)*blowfishBlockSize])
 		ciphertext = append(ciphertext, b...)
@@ -47,11 +58,11 @@ func blowfishEncrypt(src []byte, block cipher.Block) (ciphertext []byte, err err
 	return
 }
 
-func blowfishDecrypt(src []byte, block cipher.Block) (plaintext []byte, err error) {
+func blowfishLegacyDecrypt(src []byte, block cipher.Block) (plaintext []byte, err error) {
 	var b = make([]byte, blowfishBlockSize)
 	var size int
-	if len(src) % blowfishBlockSize != 0 {
-		return nil, fmt.Errorf("crypt Blowfish.Decrypt: ciphertext length must equal block size")
+	if len(src)%blowfishBlockSize != 0 {
+		return nil, ErrInvalidPadding
 	}
 	size = len(src) / blowfishBlockSize
 	plaintext = make([]byte, 0, size)
@@ -61,4 +72,4 @@ func blowfishDecrypt(src []byte, block cipher.Block) (plaintext []byte, err erro
 	}
 	plaintext, err = UnPadding(PAD_ZEROPADDING, plaintext, blowfishBlockSize)
 	return
-}
\ No newline at end of file
+}
@@ -0,0 +1,105 @@
+package crypt
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"golang.org/x/crypto/blowfish"
+)
+
+func TestBlowfishModes(t *testing.T) {
+	var key = []byte("blowfish key")
+	var text = []byte("hello blowfish\x00\x00")
+	var modes = []BlockMode{MODE_CBC, MODE_CFB, MODE_CTR, MODE_OFB, MODE_ECB, MODE_CBC_CTS, MODE_IGE, MODE_PCBC}
+	var paddings = []PaddingScheme{PAD_PKCS7, PAD_ISO97971, PAD_ANSIX923, PAD_ISO10126}
+	for _, mode := range modes {
+		for _, padding := range paddings {
+			var opts = Options{Mode: mode, Padding: padding}
+			// password and salted header, then an explicit IV
+			for _, iv := range [][]byte{nil, randBytes(derivedIVSize(blowfishBlockSize, mode))} {
+				if mode == MODE_ECB {
+					iv = nil
+				}
+				ciphertext, err := Blowfish.Encrypt(text, key, iv, opts)
+				if err != nil {
+					t.Fatal(mode, padding, err)
+				}
+				if iv == nil && mode != MODE_ECB && !bytes.HasPrefix(ciphertext, []byte(saltedText)) {
+					t.Fatal(mode, padding, "no salted header")
+				}
+				plaintext, err := Blowfish.Decrypt(ciphertext, key, iv, opts)
+				if err != nil {
+					t.Fatal(mode, padding, err)
+				}
+				if !bytes.Equal(plaintext, text) {
+					t.Fatalf("%s/%s: wrong plaintext %q", mode, padding, plaintext)
+				}
+			}
+		}
+	}
+	for _, mode := range []BlockMode{MODE_GCM, MODE_SIV, MODE_XTS} {
+		if _, err := NewBlowfish(key, nil, Options{Mode: mode}); err == nil {
+			t.Fatal(mode, "expected an error")
+		}
+	}
+	if _, err := NewBlowfish(key, randBytes(16), Options{Mode: MODE_CBC}); err == nil {
+		t.Fatal("expected an IV size error")
+	}
+}
+
+func TestBlowfishOpenSSL(t *testing.T) {
+	// openssl enc -bf-cbc -md md5, a 16 byte key
+	golden, err := os.ReadFile("testdata/openssl/bf-cbc.md5.enc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := os.ReadFile("testdata/openssl/plaintext.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	plaintext, err := Blowfish.Decrypt(golden, []byte("crypt-openssl-password"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(plaintext, want) {
+		t.Fatalf("wrong plaintext %q", plaintext)
+	}
+}
+
+func TestBlowfishLegacy(t *testing.T) {
+	var key = []byte{1, 2, 3}
+	var opts = Options{Legacy: true}
+	block, err := blowfish.NewCipher(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, text := range [][]byte{[]byte("hello blowfish"), []byte("8 bytes!")} {
+		ciphertext, err := Blowfish.Encrypt(text, key, nil, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// ECB, a full block of plaintext is not padded
+		var want = make([]byte, (len(text)+7)/8*8)
+		copy(want, text)
+		for i := 0; i < len(want); i += 8 {
+			block.Encrypt(want[i:], want[i:])
+		}
+		if !bytes.Equal(ciphertext, want) {
+			t.Fatalf("%q: got %x, want %x", text, ciphertext, want)
+		}
+		plaintext, err := Blowfish.Decrypt(ciphertext, key, nil, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(plaintext, text) {
+			t.Fatalf("wrong plaintext %q", plaintext)
+		}
+	}
+	if _, err = NewBlowfish(key, randBytes(8), opts); err == nil {
+		t.Fatal("expected an error for an IV")
+	}
+	if _, err = NewAES(randBytes(16), nil, opts); err == nil {
+		t.Fatal("expected an error for AES")
+	}
+}
@@ -0,0 +1,23 @@
+package crypt
+
+const camelliaSaltKeyByteSize = 32
+
+var Camellia cryptCamellia
+
+type cryptCamellia struct{}
+
+func (cryptCamellia) Encrypt(plaintext, key, iv []byte, args ...Options) ([]byte, error) {
+	c, err := NewCamellia(key, iv, args...)
+	if err != nil {
+		return nil, err
+	}
+	return c.Encrypt(plaintext)
+}
+
+func (cryptCamellia) Decrypt(ciphertext, key, iv []byte, args ...Options) ([]byte, error) {
+	c, err := NewCamellia(key, iv, args...)
+	if err != nil {
+		return nil, err
+	}
+	return c.Decrypt(ciphertext)
+}
@@ -0,0 +1,33 @@
+package crypt
+
+import (
+	"crypto/cipher"
+
+	"golang.org/x/crypto/cast5"
+)
+
+const cast5SaltKeyByteSize = 16
+
+var CAST5 cryptCAST5
+
+type cryptCAST5 struct{}
+
+func (cryptCAST5) Encrypt(plaintext, key, iv []byte, args ...Options) ([]byte, error) {
+	c, err := NewCAST5(key, iv, args...)
+	if err != nil {
+		return nil, err
+	}
+	return c.Encrypt(plaintext)
+}
+
+func (cryptCAST5) Decrypt(ciphertext, key, iv []byte, args ...Options) ([]byte, error) {
+	c, err := NewCAST5(key, iv, args...)
+	if err != nil {
+		return nil, err
+	}
+	return c.Decrypt(ciphertext)
+}
+
+func newCAST5(key []byte) (cipher.Block, error) {
+	return cast5.NewCipher(key)
+}
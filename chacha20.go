@@ -1,7 +1,10 @@
 package crypt
 
 import (
+	"crypto/cipher"
 	"fmt"
+	"io"
+	"math"
 
 	"github.com/Yawning/chacha20"
 )
@@ -15,53 +18,121 @@ var ChaCha20 cryptChaCha20
 
 type cryptChaCha20 struct{}
 
-func (cryptChaCha20) Encrypt(plaintext, key, iv []byte) ([]byte, error) {
-	c, err := NewChaCha20(key, iv)
+func (cryptChaCha20) Encrypt(plaintext, key, iv []byte, args ...Options) ([]byte, error) {
+	c, err := NewChaCha20(key, iv, args...)
 	if err != nil {
 		return nil, err
 	}
 	return c.Encrypt(plaintext)
 }
 
-func (cryptChaCha20) Decrypt(ciphertext, key, iv []byte) ([]byte, error) {
-	c, err := NewChaCha20(key, iv)
+func (cryptChaCha20) Decrypt(ciphertext, key, iv []byte, args ...Options) ([]byte, error) {
+	c, err := NewChaCha20(key, iv, args...)
 	if err != nil {
 		return nil, err
 	}
 	return c.Decrypt(ciphertext)
 }
 
-func chacha20Encrypt(src, key, iv []byte) (ciphertext []byte, err error) {
-	var stream *chacha20.Cipher
+// NewStreamSeeker returns the keystream for random access, see Crypt.NewStreamSeeker.
+func (cryptChaCha20) NewStreamSeeker(key, iv []byte, args ...Options) (StreamSeeker, error) {
+	c, err := NewChaCha20(key, iv, args...)
+	if err != nil {
+		return nil, err
+	}
+	return c.NewStreamSeeker()
+}
+
+// NewSealWriter returns a writer for the segmented AEAD stream, see Crypt.NewSealWriter.
+func (cryptChaCha20) NewSealWriter(w io.Writer, key, iv []byte, args ...Options) (io.WriteCloser, error) {
+	c, err := NewChaCha20(key, iv, args...)
+	if err != nil {
+		return nil, err
+	}
+	return c.NewSealWriter(w)
+}
+
+// NewOpenReader returns a reader for the segmented AEAD stream, see Crypt.NewOpenReader.
+func (cryptChaCha20) NewOpenReader(r io.Reader, key, iv []byte, args ...Options) (io.Reader, error) {
+	c, err := NewChaCha20(key, iv, args...)
+	if err != nil {
+		return nil, err
+	}
+	return c.NewOpenReader(r)
+}
+
+func chacha20Encrypt(src, key, password, iv []byte, kdf KDF, counter uint64) ([]byte, error) {
+	return streamEncrypt(src, key, password, iv, kdf, chacha20SaltNonceByteSize, chacha20SaltKeyByteSize, chacha20StreamAt(counter))
+}
+
+func chacha20Decrypt(src, key, password, iv []byte, counter uint64) ([]byte, error) {
+	return streamDecrypt(src, key, password, iv, chacha20SaltNonceByteSize, chacha20SaltKeyByteSize, chacha20StreamAt(counter))
+}
+
+func chacha20StreamAt(counter uint64) func(key, iv []byte) (cipher.Stream, error) {
+	return func(key, iv []byte) (cipher.Stream, error) {
+		return newChaCha20Stream(key, iv, counter)
+	}
+}
+
+// newChaCha20Stream returns ChaCha20 starting at block counter. An 8 byte
+// nonce is the original variant with a 64 bit counter, a 12 byte nonce RFC
+// 8439 with a 32 bit counter and a 24 byte nonce XChaCha20 with a 64 bit
+// counter.
+func newChaCha20Stream(key, iv []byte, counter uint64) (*chacha20.Cipher, error) {
+	if !inSliceInt(len(iv), []int{8, 12, 24}) {
+		return nil, nonceSizeError(METHOD_CHACHA20, len(iv), 8, 12, 24)
+	} else if len(iv) == chacha20.INonceSize && counter > math.MaxUint32 {
+		return nil, fmt.Errorf("crypt ChaCha20: counter %d does not fit the 32 bit counter of a 12 byte nonce", counter)
+	}
+	stream, err := chacha20.NewCipher(key, iv)
+	if err != nil {
+		return nil, err
+	}
+	if counter != 0 {
+		if err = stream.Seek(counter); err != nil {
+			return nil, err
+		}
+	}
+	return stream, nil
+}
+
+// streamEncrypt encrypts src with a stream cipher. Without an IV the key is a
+// password and the key and IV are derived from a salted header.
+func streamEncrypt(src, key, password, iv []byte, kdf KDF, ivSize, keySize int, newStream func(key, iv []byte) (cipher.Stream, error)) (ciphertext []byte, err error) {
+	var stream cipher.Stream
 	var offset int
 	if iv == nil {
-		var header [16]byte
-		header, key, iv = genSaltHeader(key, chacha20SaltNonceByteSize, 0, chacha20SaltKeyByteSize)
-		ciphertext = append(header[:], src...)
-		offset = 16
+		var header []byte
+		if header, key, iv, err = genHeader(kdf, key, password, ivSize, keySize); err != nil {
+			return nil, err
+		}
+		ciphertext = append(header, src...)
+		offset = len(header)
 	} else {
 		ciphertext = append([]byte{}, src...)
 	}
-	if stream, err = chacha20.NewCipher(key, iv); err != nil {
+	if stream, err = newStream(key, iv); err != nil {
 		return nil, err
 	}
 	stream.XORKeyStream(ciphertext[offset:], src)
 	return
 }
 
-func chacha20Decrypt(src, key, iv []byte) (plaintext []byte, err error) {
-	var stream *chacha20.Cipher
+func streamDecrypt(src, key, password, iv []byte, ivSize, keySize int, newStream func(key, iv []byte) (cipher.Stream, error)) (plaintext []byte, err error) {
+	var stream cipher.Stream
 	var ciphertext []byte
-	if salt, ok := getSalt(src); ok {
-		key, iv = parseSaltHeader(salt, key, chacha20SaltNonceByteSize, 0, chacha20SaltKeyByteSize)
-		ciphertext = append([]byte{}, src[16:]...)
+	var offset int
+	var derivedKey, derivedIV []byte
+	if offset, derivedKey, derivedIV, err = parseHeader(src, key, password, ivSize, keySize); err != nil {
+		return nil, err
+	} else if offset > 0 {
+		key, iv = derivedKey, derivedIV
+		ciphertext = src[offset:]
 	} else {
-		ciphertext = append([]byte{}, src...)
+		ciphertext = src
 	}
-
-	if !inSliceInt(len(iv), []int{8, 12, 24}) {
-		return nil, fmt.Errorf("crypt ChaCha20.Decrypt: invalid nonce size %d", len(iv))
-	} else if stream, err = chacha20.NewCipher(key, iv); err != nil {
+	if stream, err = newStream(key, iv); err != nil {
 		return nil, err
 	}
 	plaintext = make([]byte, len(ciphertext))
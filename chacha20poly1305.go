@@ -0,0 +1,100 @@
+package crypt
+
+import (
+	"crypto/cipher"
+	"fmt"
+
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+var ChaCha20Poly1305 cryptChaCha20Poly1305
+
+type cryptChaCha20Poly1305 struct{}
+
+func (cryptChaCha20Poly1305) Encrypt(plaintext, key, nonce []byte, args ...Options) ([]byte, error) {
+	c, err := NewChaCha20Poly1305(key, nonce, args...)
+	if err != nil {
+		return nil, err
+	}
+	return c.Encrypt(plaintext)
+}
+
+func (cryptChaCha20Poly1305) Decrypt(ciphertext, key, nonce []byte, args ...Options) ([]byte, error) {
+	c, err := NewChaCha20Poly1305(key, nonce, args...)
+	if err != nil {
+		return nil, err
+	}
+	return c.Decrypt(ciphertext)
+}
+
+var XChaCha20Poly1305 cryptXChaCha20Poly1305
+
+type cryptXChaCha20Poly1305 struct{}
+
+func (cryptXChaCha20Poly1305) Encrypt(plaintext, key, nonce []byte, args ...Options) ([]byte, error) {
+	c, err := NewXChaCha20Poly1305(key, nonce, args...)
+	if err != nil {
+		return nil, err
+	}
+	return c.Encrypt(plaintext)
+}
+
+func (cryptXChaCha20Poly1305) Decrypt(ciphertext, key, nonce []byte, args ...Options) ([]byte, error) {
+	c, err := NewXChaCha20Poly1305(key, nonce, args...)
+	if err != nil {
+		return nil, err
+	}
+	return c.Decrypt(ciphertext)
+}
+
+// chacha20Poly1305NonceSize returns the nonce size of the ChaCha20-Poly1305
+// methods, which is also the size derived from a salted header.
+func chacha20Poly1305NonceSize(method CipherMethod) int {
+	if method == METHOD_XCHACHA20POLY1305 {
+		return chacha20poly1305.NonceSizeX
+	}
+	return chacha20poly1305.NonceSize
+}
+
+func newChaCha20Poly1305(method CipherMethod, key []byte) (cipher.AEAD, error) {
+	if method == METHOD_XCHACHA20POLY1305 {
+		return chacha20poly1305.NewX(key)
+	}
+	return chacha20poly1305.New(key)
+}
+
+func chacha20Poly1305Encrypt(method CipherMethod, src, key, password, nonce, aad []byte, kdf KDF) (ciphertext []byte, err error) {
+	var aead cipher.AEAD
+	if nonce == nil {
+		if ciphertext, key, nonce, err = genHeader(kdf, key, password, chacha20Poly1305NonceSize(method), chacha20SaltKeyByteSize); err != nil {
+			return nil, err
+		}
+	}
+	if aead, err = newChaCha20Poly1305(method, key); err != nil {
+		return nil, err
+	}
+	return aead.Seal(ciphertext, nonce, src, aad), nil
+}
+
+func chacha20Poly1305Decrypt(method CipherMethod, src, key, password, nonce, aad []byte) (plaintext []byte, err error) {
+	var aead cipher.AEAD
+	var ciphertext = src
+	var offset int
+	var derivedKey, derivedNonce []byte
+	if offset, derivedKey, derivedNonce, err = parseHeader(src, key, password, chacha20Poly1305NonceSize(method), chacha20SaltKeyByteSize); err != nil {
+		return nil, err
+	} else if offset > 0 {
+		key, nonce = derivedKey, derivedNonce
+		ciphertext = src[offset:]
+	}
+	if len(nonce) != chacha20Poly1305NonceSize(method) {
+		return nil, nonceSizeError(method, len(nonce), chacha20Poly1305NonceSize(method))
+	}
+	if aead, err = newChaCha20Poly1305(method, key); err != nil {
+		return nil, err
+	}
+	if plaintext, err = aead.Open(nil, nonce, ciphertext, aad); err != nil {
+		return nil, fmt.Errorf("crypt %s.Decrypt: %w", method, ErrAuthentication)
+	}
+	return plaintext, nil
+}
@@ -0,0 +1,18 @@
+package cipher
+
+import "errors"
+
+var errOpen = errors.New("crypt/cipher: message authentication failed")
+
+// sliceForAppend extends in by n bytes and returns the whole slice and the
+// extension, as crypto/cipher does.
+func sliceForAppend(in []byte, n int) (head, tail []byte) {
+	if total := len(in) + n; cap(in) >= total {
+		head = in[:total]
+	} else {
+		head = make([]byte, total)
+		copy(head, in)
+	}
+	tail = head[len(in):]
+	return
+}
@@ -0,0 +1,140 @@
+package cipher
+
+import (
+	"crypto/cipher"
+	"crypto/subtle"
+	"encoding/binary"
+	"errors"
+)
+
+type ccm struct {
+	b         cipher.Block
+	nonceSize int
+	tagSize   int
+}
+
+// NewCCM returns the Counter with CBC-MAC mode of NIST SP 800-38C and RFC 3610
+// with a 128-bit block cipher. nonceSize is 7 to 13 bytes, which limits the
+// message to 2^(8*(15-nonceSize)) bytes, and tagSize is 4 to 16 and even.
+func NewCCM(b cipher.Block, nonceSize, tagSize int) (cipher.AEAD, error) {
+	if b.BlockSize() != 16 {
+		return nil, errors.New("crypt/cipher: CCM requires a 128-bit block cipher")
+	}
+	if nonceSize < 7 || nonceSize > 13 {
+		return nil, errors.New("crypt/cipher: invalid CCM nonce size")
+	}
+	if tagSize < 4 || tagSize > 16 || tagSize%2 != 0 {
+		return nil, errors.New("crypt/cipher: invalid CCM tag size")
+	}
+	return &ccm{b: b, nonceSize: nonceSize, tagSize: tagSize}, nil
+}
+
+func (c *ccm) NonceSize() int { return c.nonceSize }
+func (c *ccm) Overhead() int  { return c.tagSize }
+
+func (c *ccm) maxLength() uint64 {
+	var l = 15 - c.nonceSize
+	if l >= 8 {
+		return 1<<64 - 1
+	}
+	return 1<<(8*uint(l)) - 1
+}
+
+// counter returns the counter block A_i for i = 0.
+func (c *ccm) counter(nonce []byte) []byte {
+	var ctr = make([]byte, 16)
+	ctr[0] = byte(15 - c.nonceSize - 1)
+	copy(ctr[1:], nonce)
+	return ctr
+}
+
+// mac returns the unencrypted tag T over the nonce, additional data and
+// plaintext.
+func (c *ccm) mac(nonce, plaintext, additionalData []byte) []byte {
+	var l = 15 - c.nonceSize
+	var x = make([]byte, 16)
+	x[0] = byte((c.tagSize-2)/2<<3 | (l - 1))
+	if len(additionalData) > 0 {
+		x[0] |= 0x40
+	}
+	copy(x[1:], nonce)
+	var size = uint64(len(plaintext))
+	for i := 15; i > c.nonceSize; i-- {
+		x[i] = byte(size)
+		size >>= 8
+	}
+	c.b.Encrypt(x, x)
+
+	var block = func(p []byte) {
+		for len(p) > 0 {
+			n := subtle.XORBytes(x, x, p)
+			p = p[n:]
+			c.b.Encrypt(x, x)
+		}
+	}
+	if len(additionalData) > 0 {
+		var encoded []byte
+		switch n := uint64(len(additionalData)); {
+		case n < 1<<16-1<<8:
+			encoded = binary.BigEndian.AppendUint16(nil, uint16(n))
+		case n <= 1<<32-1:
+			encoded = binary.BigEndian.AppendUint32([]byte{0xff, 0xfe}, uint32(n))
+		default:
+			encoded = binary.BigEndian.AppendUint64([]byte{0xff, 0xff}, n)
+		}
+		encoded = append(encoded, additionalData...)
+		block(zeroPad(encoded))
+	}
+	block(zeroPad(plaintext))
+	return x[:c.tagSize]
+}
+
+func (c *ccm) Seal(dst, nonce, plaintext, additionalData []byte) []byte {
+	if len(nonce) != c.nonceSize {
+		panic("crypt/cipher: incorrect nonce length given to CCM")
+	}
+	if uint64(len(plaintext)) > c.maxLength() {
+		panic("crypt/cipher: message too large for CCM")
+	}
+	ret, out := sliceForAppend(dst, len(plaintext)+c.tagSize)
+	var tag = c.mac(nonce, plaintext, additionalData)
+	var ctr = c.counter(nonce)
+	var s0 = make([]byte, 16)
+	c.b.Encrypt(s0, ctr)
+	ctr[15] = 1
+	cipher.NewCTR(c.b, ctr).XORKeyStream(out, plaintext)
+	subtle.XORBytes(out[len(plaintext):], tag, s0)
+	return ret
+}
+
+func (c *ccm) Open(dst, nonce, ciphertext, additionalData []byte) ([]byte, error) {
+	if len(nonce) != c.nonceSize {
+		panic("crypt/cipher: incorrect nonce length given to CCM")
+	}
+	if len(ciphertext) < c.tagSize || uint64(len(ciphertext)-c.tagSize) > c.maxLength() {
+		return nil, errOpen
+	}
+	var tag = ciphertext[len(ciphertext)-c.tagSize:]
+	ciphertext = ciphertext[:len(ciphertext)-c.tagSize]
+	ret, out := sliceForAppend(dst, len(ciphertext))
+	var ctr = c.counter(nonce)
+	var s0 = make([]byte, 16)
+	c.b.Encrypt(s0, ctr)
+	ctr[15] = 1
+	cipher.NewCTR(c.b, ctr).XORKeyStream(out, ciphertext)
+	var expected = c.mac(nonce, out, additionalData)
+	subtle.XORBytes(expected, expected, s0)
+	if subtle.ConstantTimeCompare(expected, tag) != 1 {
+		clear(out)
+		return nil, errOpen
+	}
+	return ret, nil
+}
+
+// zeroPad pads p with zeros to a multiple of 16 bytes.
+func zeroPad(p []byte) []byte {
+	if len(p)%16 == 0 {
+		return p
+	}
+	return append(p[:len(p):len(p)], make([]byte, 16-len(p)%16)...)
+}
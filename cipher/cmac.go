@@ -0,0 +1,91 @@
+package cipher
+
+import (
+	"crypto/cipher"
+	"crypto/subtle"
+	"errors"
+	"hash"
+)
+
+var errCMACBlockSize = errors.New("crypt/cipher: CMAC requires a 64-bit or 128-bit block cipher")
+
+type cmac struct {
+	b      cipher.Block
+	k1, k2 []byte
+	x      []byte
+	buf    []byte
+}
+
+// NewCMAC returns CMAC (NIST SP 800-38B, RFC 4493 for AES) computed with b,
+// which must have a 64-bit or 128-bit block size.
+func NewCMAC(b cipher.Block) (hash.Hash, error) {
+	var bs = b.BlockSize()
+	var rb byte
+	switch bs {
+	case 8:
+		rb = 0x1b
+	case 16:
+		rb = 0x87
+	default:
+		return nil, errCMACBlockSize
+	}
+	var l = make([]byte, bs)
+	b.Encrypt(l, l)
+	var k1 = shiftLeft(l, rb)
+	var k2 = shiftLeft(k1, rb)
+	return &cmac{b: b, k1: k1, k2: k2, x: make([]byte, bs), buf: make([]byte, 0, bs)}, nil
+}
+
+// shiftLeft doubles v in GF(2^n) with the reduction constant rb.
+func shiftLeft(v []byte, rb byte) []byte {
+	var out = make([]byte, len(v))
+	var carry byte
+	for i := len(v) - 1; i >= 0; i-- {
+		out[i] = v[i]<<1 | carry
+		carry = v[i] >> 7
+	}
+	out[len(v)-1] ^= byte(subtle.ConstantTimeByteEq(carry, 1)) * rb
+	return out
+}
+
+func (m *cmac) Size() int      { return m.b.BlockSize() }
+func (m *cmac) BlockSize() int { return m.b.BlockSize() }
+
+func (m *cmac) Reset() {
+	for i := range m.x {
+		m.x[i] = 0
+	}
+	m.buf = m.buf[:0]
+}
+
+func (m *cmac) Write(p []byte) (int, error) {
+	var n = len(p)
+	var bs = m.b.BlockSize()
+	for len(p) > 0 {
+		// the last block is kept back for Sum, even when it is complete
+		if len(m.buf) == bs {
+			subtle.XORBytes(m.x, m.x, m.buf)
+			m.b.Encrypt(m.x, m.x)
+			m.buf = m.buf[:0]
+		}
+		c := copy(m.buf[len(m.buf):bs], p)
+		m.buf = m.buf[:len(m.buf)+c]
+		p = p[c:]
+	}
+	return n, nil
+}
+
+func (m *cmac) Sum(in []byte) []byte {
+	var bs = m.b.BlockSize()
+	var last = make([]byte, bs)
+	copy(last, m.buf)
+	if len(m.buf) == bs {
+		subtle.XORBytes(last, last, m.k1)
+	} else {
+		last[len(m.buf)] = 0x80
+		subtle.XORBytes(last, last, m.k2)
+	}
+	subtle.XORBytes(last, last, m.x)
+	m.b.Encrypt(last, last)
+	return append(in, last...)
+}
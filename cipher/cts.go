@@ -0,0 +1,128 @@
+package cipher
+
+import (
+	"crypto/cipher"
+	"crypto/subtle"
+)
+
+// Ciphertext stealing variants of the NIST SP 800-38A Addendum. They only
+// differ in the order of the last two ciphertext blocks.
+const (
+	// CS1 keeps the partial block before the last full one.
+	CS1 = 1
+	// CS2 is CBC when the input is full blocks, CS3 otherwise.
+	CS2 = 2
+	// CS3 always swaps the last two blocks, as Kerberos (RFC 3962) does.
+	CS3 = 3
+)
+
+type cts struct {
+	b         cipher.Block
+	blockSize int
+	iv        []byte
+	variant   int
+}
+
+func newCTS(b cipher.Block, iv []byte, variant int) *cts {
+	if len(iv) != b.BlockSize() {
+		panic("crypt/cipher: IV length must equal block size")
+	}
+	if variant < CS1 || variant > CS3 {
+		panic("crypt/cipher: unknown CTS variant")
+	}
+	return &cts{b: b, blockSize: b.BlockSize(), iv: append([]byte{}, iv...), variant: variant}
+}
+
+// swapped reports whether the last full block comes before the partial one,
+// d is the size of the partial block.
+func (x *cts) swapped(d int) bool {
+	return x.variant == CS3 || (x.variant == CS2 && d != x.blockSize)
+}
+
+// check validates the lengths and returns the size d of the last, possibly
+// partial, block and the number of bytes before the last two blocks.
+func (x *cts) check(dst, src []byte) (d, head int) {
+	if len(src) < x.blockSize {
+		panic("crypt/cipher: CTS input shorter than a block")
+	}
+	if len(dst) < len(src) {
+		panic("crypt/cipher: output smaller than input")
+	}
+	if d = len(src) % x.blockSize; d == 0 {
+		d = x.blockSize
+	}
+	return d, len(src) - d - x.blockSize
+}
+
+type ctsEncrypter cts
+
+// NewCBCCTSEncrypter returns CBC with ciphertext stealing in the given
+// variant, so the ciphertext is as long as the plaintext. Unlike CBC, every
+// CryptBlocks call encrypts a whole message of at least one block, starting
+// from iv.
+func NewCBCCTSEncrypter(b cipher.Block, iv []byte, variant int) cipher.BlockMode {
+	return (*ctsEncrypter)(newCTS(b, iv, variant))
+}
+
+func (x *ctsEncrypter) BlockSize() int { return x.blockSize }
+
+func (x *ctsEncrypter) CryptBlocks(dst, src []byte) {
+	var bs = x.blockSize
+	if len(src) == bs {
+		cipher.NewCBCEncrypter(x.b, x.iv).CryptBlocks(dst, src)
+		return
+	}
+	d, head := (*cts)(x).check(dst, src)
+	// the partial block is padded with zeros, the stolen ciphertext
+	var last = make([]byte, 2*bs)
+	copy(last, src[head:])
+	cbc := cipher.NewCBCEncrypter(x.b, x.iv)
+	cbc.CryptBlocks(dst[:head], src[:head])
+	cbc.CryptBlocks(last, last)
+	if (*cts)(x).swapped(d) {
+		copy(dst[head:], last[bs:])
+		copy(dst[head+bs:], last[:d])
+	} else {
+		copy(dst[head:], last[:d])
+		copy(dst[head+d:], last[bs:])
+	}
+}
+
+type ctsDecrypter cts
+
+// NewCBCCTSDecrypter returns the decrypter of NewCBCCTSEncrypter.
+func NewCBCCTSDecrypter(b cipher.Block, iv []byte, variant int) cipher.BlockMode {
+	return (*ctsDecrypter)(newCTS(b, iv, variant))
+}
+
+func (x *ctsDecrypter) BlockSize() int { return x.blockSize }
+
+func (x *ctsDecrypter) CryptBlocks(dst, src []byte) {
+	var bs = x.blockSize
+	if len(src) == bs {
+		cipher.NewCBCDecrypter(x.b, x.iv).CryptBlocks(dst, src)
+		return
+	}
+	d, head := (*cts)(x).check(dst, src)
+	var tail = append([]byte{}, src[head:]...)
+	var partial, full []byte
+	if (*cts)(x).swapped(d) {
+		full, partial = tail[:bs], tail[bs:]
+	} else {
+		partial, full = tail[:d], tail[d:]
+	}
+	var prev = x.iv
+	if head > 0 {
+		prev = append([]byte{}, src[head-bs:head]...)
+	}
+	cipher.NewCBCDecrypter(x.b, x.iv).CryptBlocks(dst[:head], src[:head])
+
+	// the last block decrypts to the final plaintext XOR the partial block,
+	// followed by the bytes stolen from it
+	var z = make([]byte, bs)
+	x.b.Decrypt(z, full)
+	var stolen = append(append([]byte{}, partial...), z[d:]...)
+	subtle.XORBytes(dst[head+bs:head+bs+d], z[:d], partial)
+	x.b.Decrypt(dst[head:head+bs], stolen)
+	subtle.XORBytes(dst[head:head+bs], dst[head:head+bs], prev)
+}
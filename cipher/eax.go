@@ -0,0 +1,82 @@
+package cipher
+
+import (
+	"crypto/cipher"
+	"crypto/subtle"
+	"errors"
+	"hash"
+)
+
+type eax struct {
+	b         cipher.Block
+	nonceSize int
+	tagSize   int
+}
+
+// NewEAX returns the EAX mode of Bellare, Rogaway and Wagner with a 64-bit or
+// 128-bit block cipher. nonceSize is at least 1 and tagSize 1 to the block
+// size.
+func NewEAX(b cipher.Block, nonceSize, tagSize int) (cipher.AEAD, error) {
+	if _, err := NewCMAC(b); err != nil {
+		return nil, err
+	}
+	if nonceSize < 1 {
+		return nil, errors.New("crypt/cipher: invalid EAX nonce size")
+	}
+	if tagSize < 1 || tagSize > b.BlockSize() {
+		return nil, errors.New("crypt/cipher: invalid EAX tag size")
+	}
+	return &eax{b: b, nonceSize: nonceSize, tagSize: tagSize}, nil
+}
+
+func (e *eax) NonceSize() int { return e.nonceSize }
+func (e *eax) Overhead() int  { return e.tagSize }
+
+// omac is OMAC^t_K(data), CMAC of data prefixed by a block encoding t. A new
+// CMAC is used for every call so the AEAD is safe for concurrent use.
+func (e *eax) omac(t byte, data []byte) []byte {
+	var mac hash.Hash
+	mac, _ = NewCMAC(e.b)
+	var prefix = make([]byte, e.b.BlockSize())
+	prefix[len(prefix)-1] = t
+	mac.Write(prefix)
+	mac.Write(data)
+	return mac.Sum(nil)
+}
+
+func (e *eax) Seal(dst, nonce, plaintext, additionalData []byte) []byte {
+	if len(nonce) != e.nonceSize {
+		panic("crypt/cipher: incorrect nonce length given to EAX")
+	}
+	ret, out := sliceForAppend(dst, len(plaintext)+e.tagSize)
+	var n = e.omac(0, nonce)
+	var h = e.omac(1, additionalData)
+	cipher.NewCTR(e.b, n).XORKeyStream(out, plaintext)
+	var tag = e.omac(2, out[:len(plaintext)])
+	subtle.XORBytes(tag, tag, n)
+	subtle.XORBytes(tag, tag, h)
+	copy(out[len(plaintext):], tag[:e.tagSize])
+	return ret
+}
+
+func (e *eax) Open(dst, nonce, ciphertext, additionalData []byte) ([]byte, error) {
+	if len(nonce) != e.nonceSize {
+		panic("crypt/cipher: incorrect nonce length given to EAX")
+	}
+	if len(ciphertext) < e.tagSize {
+		return nil, errOpen
+	}
+	var tag = ciphertext[len(ciphertext)-e.tagSize:]
+	ciphertext = ciphertext[:len(ciphertext)-e.tagSize]
+	var n = e.omac(0, nonce)
+	var h = e.omac(1, additionalData)
+	var expected = e.omac(2, ciphertext)
+	subtle.XORBytes(expected, expected, n)
+	subtle.XORBytes(expected, expected, h)
+	if subtle.ConstantTimeCompare(expected[:e.tagSize], tag) != 1 {
+		return nil, errOpen
+	}
+	ret, out := sliceForAppend(dst, len(ciphertext))
+	cipher.NewCTR(e.b, n).XORKeyStream(out, ciphertext)
+	return ret, nil
+}
@@ -0,0 +1,162 @@
+package cipher
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/subtle"
+	"encoding/binary"
+	"errors"
+)
+
+const (
+	gcmSIVNonceSize = 12
+	gcmSIVTagSize   = 16
+	gcmSIVMaxLength = 1 << 36
+)
+
+type gcmSIV struct {
+	key []byte
+}
+
+// NewGCMSIV returns AES-GCM-SIV of RFC 8452, a nonce misuse resistant AEAD,
+// for a 16 or 32 byte AES key. The nonce is 12 bytes and the tag 16.
+func NewGCMSIV(key []byte) (cipher.AEAD, error) {
+	if len(key) != 16 && len(key) != 32 {
+		return nil, errors.New("crypt/cipher: GCM-SIV requires a 16 or 32 byte key")
+	}
+	return &gcmSIV{key: append([]byte{}, key...)}, nil
+}
+
+func (g *gcmSIV) NonceSize() int { return gcmSIVNonceSize }
+func (g *gcmSIV) Overhead() int  { return gcmSIVTagSize }
+
+// deriveKeys returns the per nonce message authentication and encryption
+// keys of RFC 8452 section 4.
+func (g *gcmSIV) deriveKeys(nonce []byte) (authKey []byte, block cipher.Block) {
+	var b, _ = aes.NewCipher(g.key)
+	var in = make([]byte, 16)
+	var out = make([]byte, 16)
+	copy(in[4:], nonce)
+	var derived = make([]byte, 0, 16+len(g.key))
+	for i := uint32(0); len(derived) < 16+len(g.key); i++ {
+		binary.LittleEndian.PutUint32(in, i)
+		b.Encrypt(out, in)
+		derived = append(derived, out[:8]...)
+	}
+	block, _ = aes.NewCipher(derived[16:])
+	return derived[:16], block
+}
+
+// tag computes the tag over the POLYVAL of the additional data and plaintext.
+func (g *gcmSIV) tag(authKey []byte, block cipher.Block, nonce, plaintext, additionalData []byte) []byte {
+	var p = newPolyval(authKey)
+	p.update(additionalData)
+	p.update(plaintext)
+	var lengths = make([]byte, 16)
+	binary.LittleEndian.PutUint64(lengths, uint64(len(additionalData))*8)
+	binary.LittleEndian.PutUint64(lengths[8:], uint64(len(plaintext))*8)
+	p.update(lengths)
+	var s = p.sum()
+	subtle.XORBytes(s, s, nonce)
+	s[15] &= 0x7f
+	block.Encrypt(s, s)
+	return s
+}
+
+// ctr is AES-CTR with the 32-bit little endian counter of GCM-SIV.
+func (g *gcmSIV) ctr(block cipher.Block, tag, dst, src []byte) {
+	var counter = append([]byte{}, tag...)
+	counter[15] |= 0x80
+	var ks = make([]byte, 16)
+	for len(src) > 0 {
+		block.Encrypt(ks, counter)
+		n := subtle.XORBytes(dst, src, ks)
+		dst, src = dst[n:], src[n:]
+		binary.LittleEndian.PutUint32(counter, binary.LittleEndian.Uint32(counter)+1)
+	}
+}
+
+func (g *gcmSIV) Seal(dst, nonce, plaintext, additionalData []byte) []byte {
+	if len(nonce) != gcmSIVNonceSize {
+		panic("crypt/cipher: incorrect nonce length given to GCM-SIV")
+	}
+	if uint64(len(plaintext)) > gcmSIVMaxLength || uint64(len(additionalData)) > gcmSIVMaxLength {
+		panic("crypt/cipher: message too large for GCM-SIV")
+	}
+	authKey, block := g.deriveKeys(nonce)
+	var tag = g.tag(authKey, block, nonce, plaintext, additionalData)
+	ret, out := sliceForAppend(dst, len(plaintext)+gcmSIVTagSize)
+	g.ctr(block, tag, out, plaintext)
+	copy(out[len(plaintext):], tag)
+	return ret
+}
+
+func (g *gcmSIV) Open(dst, nonce, ciphertext, additionalData []byte) ([]byte, error) {
+	if len(nonce) != gcmSIVNonceSize {
+		panic("crypt/cipher: incorrect nonce length given to GCM-SIV")
+	}
+	if len(ciphertext) < gcmSIVTagSize || uint64(len(ciphertext)) > gcmSIVMaxLength+gcmSIVTagSize {
+		return nil, errOpen
+	}
+	var tag = ciphertext[len(ciphertext)-gcmSIVTagSize:]
+	ciphertext = ciphertext[:len(ciphertext)-gcmSIVTagSize]
+	authKey, block := g.deriveKeys(nonce)
+	ret, out := sliceForAppend(dst, len(ciphertext))
+	g.ctr(block, tag, out, ciphertext)
+	var expected = g.tag(authKey, block, nonce, out, additionalData)
+	if subtle.ConstantTimeCompare(expected, tag) != 1 {
+		clear(out)
+		return nil, errOpen
+	}
+	return ret, nil
+}
+
+// polyval is POLYVAL of RFC 8452 section 3, in GF(2^128) defined by
+// x^128 + x^127 + x^126 + x^121 + 1 with little endian field elements.
+type polyval struct {
+	h [2]uint64
+	s [2]uint64
+}
+
+func newPolyval(key []byte) *polyval {
+	return &polyval{h: [2]uint64{binary.LittleEndian.Uint64(key), binary.LittleEndian.Uint64(key[8:])}}
+}
+
+// update absorbs p zero padded to a multiple of 16 bytes.
+func (p *polyval) update(data []byte) {
+	var block = make([]byte, 16)
+	for len(data) > 0 {
+		clear(block)
+		n := copy(block, data)
+		data = data[n:]
+		p.s[0] ^= binary.LittleEndian.Uint64(block)
+		p.s[1] ^= binary.LittleEndian.Uint64(block[8:])
+		p.s = dot(p.s, p.h)
+	}
+}
+
+func (p *polyval) sum() []byte {
+	var out = make([]byte, 16)
+	binary.LittleEndian.PutUint64(out, p.s[0])
+	binary.LittleEndian.PutUint64(out[8:], p.s[1])
+	return out
+}
+
+// dot returns a*b*x^-128, adding b for every set bit of a and dividing by x
+// after each step.
+func dot(a, b [2]uint64) [2]uint64 {
+	var r [2]uint64
+	for i := 0; i < 128; i++ {
+		bit := a[i/64] >> uint(i%64) & 1
+		mask := -bit
+		r[0] ^= b[0] & mask
+		r[1] ^= b[1] & mask
+		// multiply by x^-1: add the polynomial if r is odd, then shift
+		carry := r[0] & 1
+		r[0] ^= carry
+		r[1] ^= 0xc200000000000000 & -carry
+		r[0] = r[0]>>1 | r[1]<<63
+		r[1] = r[1]>>1 | carry<<63
+	}
+	return r
+}
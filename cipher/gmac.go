@@ -0,0 +1,130 @@
+package cipher
+
+import (
+	"crypto/cipher"
+	"encoding/binary"
+	"errors"
+	"hash"
+)
+
+var (
+	errGMACBlockSize = errors.New("crypt/cipher: GMAC requires a 128-bit block cipher")
+	errGMACNonce     = errors.New("crypt/cipher: GMAC nonce must not be empty")
+)
+
+// gmac is GCM with only additional data, the tag is GHASH of the data
+// encrypted with the counter block J0.
+type gmac struct {
+	h    [2]uint64
+	j0   [16]byte
+	y    [2]uint64
+	buf  []byte
+	size uint64
+}
+
+// NewGMAC returns GMAC (NIST SP 800-38D) computed with b, which must have a
+// 128-bit block size. A nonce of any length but 12 bytes is hashed the same
+// way GCM does. The same key and nonce must never authenticate two messages.
+func NewGMAC(b cipher.Block, nonce []byte) (hash.Hash, error) {
+	if b.BlockSize() != 16 {
+		return nil, errGMACBlockSize
+	} else if len(nonce) == 0 {
+		return nil, errGMACNonce
+	}
+	var m = &gmac{buf: make([]byte, 0, 16)}
+	var h [16]byte
+	b.Encrypt(h[:], h[:])
+	m.h = [2]uint64{binary.BigEndian.Uint64(h[:]), binary.BigEndian.Uint64(h[8:])}
+	if len(nonce) == 12 {
+		copy(m.j0[:], nonce)
+		m.j0[15] = 1
+	} else {
+		var j gmac
+		j.h = m.h
+		j.Write(nonce)
+		j.final(0, uint64(len(nonce))*8)
+		binary.BigEndian.PutUint64(m.j0[:], j.y[0])
+		binary.BigEndian.PutUint64(m.j0[8:], j.y[1])
+	}
+	b.Encrypt(m.j0[:], m.j0[:])
+	return m, nil
+}
+
+func (m *gmac) Size() int      { return 16 }
+func (m *gmac) BlockSize() int { return 16 }
+
+func (m *gmac) Reset() {
+	m.y = [2]uint64{}
+	m.buf = m.buf[:0]
+	m.size = 0
+}
+
+func (m *gmac) Write(p []byte) (int, error) {
+	var n = len(p)
+	m.size += uint64(n)
+	if len(m.buf) > 0 {
+		c := copy(m.buf[len(m.buf):16], p)
+		m.buf = m.buf[:len(m.buf)+c]
+		p = p[c:]
+		if len(m.buf) < 16 {
+			return n, nil
+		}
+		m.block(m.buf)
+		m.buf = m.buf[:0]
+	}
+	for ; len(p) >= 16; p = p[16:] {
+		m.block(p)
+	}
+	m.buf = append(m.buf, p...)
+	return n, nil
+}
+
+func (m *gmac) Sum(in []byte) []byte {
+	var s = *m
+	s.final(m.size*8, 0)
+	var tag [16]byte
+	binary.BigEndian.PutUint64(tag[:], s.y[0])
+	binary.BigEndian.PutUint64(tag[8:], s.y[1])
+	for i := range tag {
+		tag[i] ^= m.j0[i]
+	}
+	return append(in, tag[:]...)
+}
+
+// final hashes the zero padded partial block and the bit lengths of the
+// additional data and the ciphertext.
+func (m *gmac) final(aadBits, ciphertextBits uint64) {
+	if len(m.buf) > 0 {
+		var last [16]byte
+		copy(last[:], m.buf)
+		m.block(last[:])
+	}
+	var lengths [16]byte
+	binary.BigEndian.PutUint64(lengths[:], aadBits)
+	binary.BigEndian.PutUint64(lengths[8:], ciphertextBits)
+	m.block(lengths[:])
+}
+
+// block sets y to (y ^ x) * h in GF(2^128) with the bit order of GCM.
+func (m *gmac) block(x []byte) {
+	var x0 = m.y[0] ^ binary.BigEndian.Uint64(x)
+	var x1 = m.y[1] ^ binary.BigEndian.Uint64(x[8:])
+	var z0, z1 uint64
+	var v0, v1 = m.h[0], m.h[1]
+	for i := 0; i < 128; i++ {
+		var bit uint64
+		if i < 64 {
+			bit = x0 >> (63 - i) & 1
+		} else {
+			bit = x1 >> (127 - i) & 1
+		}
+		// constant time: masks instead of branches on secret bits
+		var mask = -bit
+		z0 ^= v0 & mask
+		z1 ^= v1 & mask
+		var reduce = -(v1 & 1)
+		v1 = v1>>1 | v0<<63
+		v0 = v0>>1 ^ 0xe100000000000000&reduce
+	}
+	m.y = [2]uint64{z0, z1}
+}
@@ -0,0 +1,84 @@
+package cipher
+
+import (
+	"crypto/cipher"
+	"crypto/subtle"
+)
+
+// ige is the Infinite Garble Extension mode used by Telegram MTProto:
+//
+//	c[i] = E(p[i] ^ c[i-1]) ^ p[i-1]
+//
+// The IV is two blocks, c[0] followed by p[0].
+type ige struct {
+	b         cipher.Block
+	blockSize int
+	c, p      []byte
+}
+
+func newIGE(b cipher.Block, iv []byte) *ige {
+	var bs = b.BlockSize()
+	if len(iv) != 2*bs {
+		panic("crypt/cipher: IGE IV length must be twice the block size")
+	}
+	return &ige{b: b, blockSize: bs, c: append([]byte{}, iv[:bs]...), p: append([]byte{}, iv[bs:]...)}
+}
+
+type igeEncrypter ige
+
+// NewIGEEncrypter returns a BlockMode which encrypts in IGE mode with b. The
+// IV is twice the block size.
+func NewIGEEncrypter(b cipher.Block, iv []byte) cipher.BlockMode {
+	return (*igeEncrypter)(newIGE(b, iv))
+}
+
+func (x *igeEncrypter) BlockSize() int { return x.blockSize }
+
+func (x *igeEncrypter) CryptBlocks(dst, src []byte) {
+	if len(src)%x.blockSize != 0 {
+		panic("crypt/cipher: input not full blocks")
+	}
+	if len(dst) < len(src) {
+		panic("crypt/cipher: output smaller than input")
+	}
+	var block = make([]byte, x.blockSize)
+	for len(src) > 0 {
+		subtle.XORBytes(block, src[:x.blockSize], x.c)
+		x.b.Encrypt(block, block)
+		subtle.XORBytes(block, block, x.p)
+		copy(x.p, src[:x.blockSize])
+		copy(x.c, block)
+		copy(dst, block)
+		src = src[x.blockSize:]
+		dst = dst[x.blockSize:]
+	}
+}
+
+type igeDecrypter ige
+
+// NewIGEDecrypter returns a BlockMode which decrypts in IGE mode with b.
+func NewIGEDecrypter(b cipher.Block, iv []byte) cipher.BlockMode {
+	return (*igeDecrypter)(newIGE(b, iv))
+}
+
+func (x *igeDecrypter) BlockSize() int { return x.blockSize }
+
+func (x *igeDecrypter) CryptBlocks(dst, src []byte) {
+	if len(src)%x.blockSize != 0 {
+		panic("crypt/cipher: input not full blocks")
+	}
+	if len(dst) < len(src) {
+		panic("crypt/cipher: output smaller than input")
+	}
+	var block = make([]byte, x.blockSize)
+	for len(src) > 0 {
+		subtle.XORBytes(block, src[:x.blockSize], x.p)
+		x.b.Decrypt(block, block)
+		subtle.XORBytes(block, block, x.c)
+		copy(x.c, src[:x.blockSize])
+		copy(x.p, block)
+		copy(dst, block)
+		src = src[x.blockSize:]
+		dst = dst[x.blockSize:]
+	}
+}
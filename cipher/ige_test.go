@@ -0,0 +1,49 @@
+package cipher
+
+import (
+	"bytes"
+	"crypto/aes"
+	"encoding/hex"
+	"testing"
+)
+
+func TestIGE(t *testing.T) {
+	// OpenSSL IGE test vectors
+	var vectors = []struct {
+		key, iv, plaintext, ciphertext string
+	}{
+		{"000102030405060708090a0b0c0d0e0f", "000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f", "0000000000000000000000000000000000000000000000000000000000000000", "1a8519a6557be652e9da8e43da4ef4453cf456b4ca488aa383c79c98b34797cb"},
+		{"5468697320697320616e20696d706c65", "6d656e746174696f6e206f6620494745206d6f646520666f72204f70656e5353", "99706487a1cde613bc6de0b6f24b1c7aa448c8b9c3403e3467a8cad89340f53b", "4c2e204c6574277320686f70652042656e20676f74206974207269676874210a"},
+	}
+	for i, v := range vectors {
+		key, _ := hex.DecodeString(v.key)
+		iv, _ := hex.DecodeString(v.iv)
+		plaintext, _ := hex.DecodeString(v.plaintext)
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			t.Fatal(i, err)
+		}
+		// one block at a time must chain like a single call
+		var ciphertext = make([]byte, len(plaintext))
+		var enc = NewIGEEncrypter(block, iv)
+		for j := 0; j < len(plaintext); j += 16 {
+			enc.CryptBlocks(ciphertext[j:j+16], plaintext[j:j+16])
+		}
+		if hex.EncodeToString(ciphertext) != v.ciphertext {
+			t.Fatalf("%d: %x", i, ciphertext)
+		}
+		// in place
+		NewIGEDecrypter(block, iv).CryptBlocks(ciphertext, ciphertext)
+		if !bytes.Equal(ciphertext, plaintext) {
+			t.Fatalf("%d: decrypt %x", i, ciphertext)
+		}
+	}
+
+	block, _ := aes.NewCipher(make([]byte, 16))
+	defer func() {
+		if recover() == nil {
+			t.Fatal("IGE accepted a one block IV")
+		}
+	}()
+	NewIGEEncrypter(block, make([]byte, 16))
+}
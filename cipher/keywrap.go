@@ -0,0 +1,141 @@
+package cipher
+
+import (
+	"crypto/cipher"
+	"crypto/subtle"
+	"encoding/binary"
+	"errors"
+)
+
+var (
+	errKeyWrapBlockSize = errors.New("crypt/cipher: key wrap requires a 128-bit block cipher")
+	errKeyWrapLength    = errors.New("crypt/cipher: invalid key wrap input length")
+	errKeyUnwrap        = errors.New("crypt/cipher: key unwrap integrity check failed")
+)
+
+var keyWrapIV = []byte{0xa6, 0xa6, 0xa6, 0xa6, 0xa6, 0xa6, 0xa6, 0xa6}
+
+var keyWrapPadIV = []byte{0xa6, 0x59, 0x59, 0xa6}
+
+// KeyWrap wraps plaintext, at least 16 bytes and a multiple of 8, with the AES
+// Key Wrap algorithm of RFC 3394.
+func KeyWrap(b cipher.Block, plaintext []byte) ([]byte, error) {
+	if b.BlockSize() != 16 {
+		return nil, errKeyWrapBlockSize
+	}
+	if len(plaintext) < 16 || len(plaintext)%8 != 0 {
+		return nil, errKeyWrapLength
+	}
+	return wrap(b, keyWrapIV, plaintext), nil
+}
+
+// KeyUnwrap unwraps a ciphertext produced by KeyWrap.
+func KeyUnwrap(b cipher.Block, ciphertext []byte) ([]byte, error) {
+	if b.BlockSize() != 16 {
+		return nil, errKeyWrapBlockSize
+	}
+	if len(ciphertext) < 24 || len(ciphertext)%8 != 0 {
+		return nil, errKeyWrapLength
+	}
+	a, plaintext := unwrap(b, ciphertext)
+	if subtle.ConstantTimeCompare(a, keyWrapIV) != 1 {
+		return nil, errKeyUnwrap
+	}
+	return plaintext, nil
+}
+
+// KeyWrapPad wraps plaintext of any length from 1 byte with the AES Key Wrap
+// with Padding algorithm of RFC 5649.
+func KeyWrapPad(b cipher.Block, plaintext []byte) ([]byte, error) {
+	if b.BlockSize() != 16 {
+		return nil, errKeyWrapBlockSize
+	}
+	if len(plaintext) == 0 || uint64(len(plaintext)) > 0xffffffff {
+		return nil, errKeyWrapLength
+	}
+	var aiv = make([]byte, 8)
+	copy(aiv, keyWrapPadIV)
+	binary.BigEndian.PutUint32(aiv[4:], uint32(len(plaintext)))
+	var padded = make([]byte, (len(plaintext)+7)/8*8)
+	copy(padded, plaintext)
+	if len(padded) == 8 {
+		var ciphertext = append(aiv, padded...)
+		b.Encrypt(ciphertext, ciphertext)
+		return ciphertext, nil
+	}
+	return wrap(b, aiv, padded), nil
+}
+
+// KeyUnwrapPad unwraps a ciphertext produced by KeyWrapPad.
+func KeyUnwrapPad(b cipher.Block, ciphertext []byte) ([]byte, error) {
+	if b.BlockSize() != 16 {
+		return nil, errKeyWrapBlockSize
+	}
+	if len(ciphertext) < 16 || len(ciphertext)%8 != 0 {
+		return nil, errKeyWrapLength
+	}
+	var a, padded []byte
+	if len(ciphertext) == 16 {
+		var block = make([]byte, 16)
+		b.Decrypt(block, ciphertext)
+		a, padded = block[:8], block[8:]
+	} else {
+		a, padded = unwrap(b, ciphertext)
+	}
+	var size = int(binary.BigEndian.Uint32(a[4:]))
+	var ok = subtle.ConstantTimeCompare(a[:4], keyWrapPadIV)
+	ok &= subtle.ConstantTimeLessOrEq(len(padded)-7, size) & subtle.ConstantTimeLessOrEq(size, len(padded))
+	if ok != 1 {
+		return nil, errKeyUnwrap
+	}
+	var zero byte
+	for _, v := range padded[size:] {
+		zero |= v
+	}
+	if zero != 0 {
+		return nil, errKeyUnwrap
+	}
+	return padded[:size], nil
+}
+
+// wrap is the wrapping process W of RFC 3394 section 2.2.1 with the initial
+// value iv.
+func wrap(b cipher.Block, iv, plaintext []byte) []byte {
+	var n = len(plaintext) / 8
+	var ciphertext = make([]byte, 8+len(plaintext))
+	var a = ciphertext[:8]
+	var block = make([]byte, 16)
+	copy(a, iv)
+	copy(ciphertext[8:], plaintext)
+	for j := 0; j < 6; j++ {
+		for i := 1; i <= n; i++ {
+			r := ciphertext[i*8 : i*8+8]
+			copy(block, a)
+			copy(block[8:], r)
+			b.Encrypt(block, block)
+			binary.BigEndian.PutUint64(a, binary.BigEndian.Uint64(block)^uint64(n*j+i))
+			copy(r, block[8:])
+		}
+	}
+	return ciphertext
+}
+
+// unwrap is the unwrapping process W⁻¹ of RFC 3394 section 2.2.2. It returns
+// the initial value to be checked by the caller.
+func unwrap(b cipher.Block, ciphertext []byte) (a, plaintext []byte) {
+	var n = len(ciphertext)/8 - 1
+	var block = make([]byte, 16)
+	a = append([]byte{}, ciphertext[:8]...)
+	plaintext = append([]byte{}, ciphertext[8:]...)
+	for j := 5; j >= 0; j-- {
+		for i := n; i >= 1; i-- {
+			r := plaintext[(i-1)*8 : i*8]
+			binary.BigEndian.PutUint64(block, binary.BigEndian.Uint64(a)^uint64(n*j+i))
+			copy(block[8:], r)
+			b.Decrypt(block, block)
+			copy(a, block)
+			copy(r, block[8:])
+		}
+	}
+	return a, plaintext
+}
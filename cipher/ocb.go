@@ -0,0 +1,163 @@
+package cipher
+
+import (
+	"crypto/cipher"
+	"crypto/subtle"
+	"errors"
+	"math/bits"
+)
+
+type ocb struct {
+	b         cipher.Block
+	nonceSize int
+	tagSize   int
+	lStar     []byte
+	lDollar   []byte
+	l         [][]byte
+}
+
+// NewOCB returns OCB3 of RFC 7253 with a 128-bit block cipher. nonceSize is 1
+// to 15 bytes and tagSize 1 to 16.
+func NewOCB(b cipher.Block, nonceSize, tagSize int) (cipher.AEAD, error) {
+	if b.BlockSize() != 16 {
+		return nil, errors.New("crypt/cipher: OCB requires a 128-bit block cipher")
+	}
+	if nonceSize < 1 || nonceSize > 15 {
+		return nil, errors.New("crypt/cipher: invalid OCB nonce size")
+	}
+	if tagSize < 1 || tagSize > 16 {
+		return nil, errors.New("crypt/cipher: invalid OCB tag size")
+	}
+	var o = &ocb{b: b, nonceSize: nonceSize, tagSize: tagSize, lStar: make([]byte, 16)}
+	b.Encrypt(o.lStar, o.lStar)
+	o.lDollar = shiftLeft(o.lStar, 0x87)
+	// L_i for every block index of a message up to 2^64 blocks
+	o.l = make([][]byte, 64)
+	o.l[0] = shiftLeft(o.lDollar, 0x87)
+	for i := 1; i < len(o.l); i++ {
+		o.l[i] = shiftLeft(o.l[i-1], 0x87)
+	}
+	return o, nil
+}
+
+func (o *ocb) NonceSize() int { return o.nonceSize }
+func (o *ocb) Overhead() int  { return o.tagSize }
+
+// offset returns Offset_0 for nonce.
+func (o *ocb) offset(nonce []byte) []byte {
+	var n = make([]byte, 16)
+	copy(n[16-len(nonce):], nonce)
+	n[15-len(nonce)] |= 1
+	n[0] |= byte(o.tagSize*8%128) << 1
+	var bottom = int(n[15] & 0x3f)
+	n[15] &^= 0x3f
+	var stretch = make([]byte, 24)
+	o.b.Encrypt(stretch, n)
+	subtle.XORBytes(stretch[16:], stretch[:8], stretch[1:9])
+	var offset = make([]byte, 16)
+	var shift, rem = bottom / 8, uint(bottom % 8)
+	for i := range offset {
+		offset[i] = stretch[i+shift] << rem
+		if rem > 0 {
+			offset[i] |= stretch[i+shift+1] >> (8 - rem)
+		}
+	}
+	return offset
+}
+
+// hash is HASH(K, A).
+func (o *ocb) hash(a []byte) []byte {
+	var sum = make([]byte, 16)
+	var offset = make([]byte, 16)
+	var block = make([]byte, 16)
+	var i = 1
+	for ; len(a) >= 16; i++ {
+		subtle.XORBytes(offset, offset, o.l[bits.TrailingZeros(uint(i))])
+		subtle.XORBytes(block, a[:16], offset)
+		o.b.Encrypt(block, block)
+		subtle.XORBytes(sum, sum, block)
+		a = a[16:]
+	}
+	if len(a) > 0 {
+		subtle.XORBytes(offset, offset, o.lStar)
+		clear(block)
+		copy(block, a)
+		block[len(a)] = 0x80
+		subtle.XORBytes(block, block, offset)
+		o.b.Encrypt(block, block)
+		subtle.XORBytes(sum, sum, block)
+	}
+	return sum
+}
+
+// crypt encrypts or decrypts src into dst and returns the full tag.
+func (o *ocb) crypt(dst, nonce, src, additionalData []byte, encrypt bool) []byte {
+	var offset = o.offset(nonce)
+	var checksum = make([]byte, 16)
+	var block = make([]byte, 16)
+	var i = 1
+	for ; len(src) >= 16; i++ {
+		subtle.XORBytes(offset, offset, o.l[bits.TrailingZeros(uint(i))])
+		subtle.XORBytes(block, src[:16], offset)
+		if encrypt {
+			subtle.XORBytes(checksum, checksum, src[:16])
+			o.b.Encrypt(block, block)
+		} else {
+			o.b.Decrypt(block, block)
+		}
+		subtle.XORBytes(dst[:16], block, offset)
+		if !encrypt {
+			subtle.XORBytes(checksum, checksum, dst[:16])
+		}
+		src, dst = src[16:], dst[16:]
+	}
+	if len(src) > 0 {
+		subtle.XORBytes(offset, offset, o.lStar)
+		var pad = make([]byte, 16)
+		o.b.Encrypt(pad, offset)
+		clear(block)
+		if encrypt {
+			copy(block, src)
+		}
+		subtle.XORBytes(dst, src, pad)
+		if !encrypt {
+			copy(block, dst[:len(src)])
+		}
+		block[len(src)] = 0x80
+		subtle.XORBytes(checksum, checksum, block)
+	}
+	var tag = make([]byte, 16)
+	subtle.XORBytes(tag, checksum, offset)
+	subtle.XORBytes(tag, tag, o.lDollar)
+	o.b.Encrypt(tag, tag)
+	subtle.XORBytes(tag, tag, o.hash(additionalData))
+	return tag
+}
+
+func (o *ocb) Seal(dst, nonce, plaintext, additionalData []byte) []byte {
+	if len(nonce) != o.nonceSize {
+		panic("crypt/cipher: incorrect nonce length given to OCB")
+	}
+	ret, out := sliceForAppend(dst, len(plaintext)+o.tagSize)
+	var tag = o.crypt(out, nonce, plaintext, additionalData, true)
+	copy(out[len(plaintext):], tag)
+	return ret
+}
+
+func (o *ocb) Open(dst, nonce, ciphertext, additionalData []byte) ([]byte, error) {
+	if len(nonce) != o.nonceSize {
+		panic("crypt/cipher: incorrect nonce length given to OCB")
+	}
+	if len(ciphertext) < o.tagSize {
+		return nil, errOpen
+	}
+	var tag = ciphertext[len(ciphertext)-o.tagSize:]
+	ciphertext = ciphertext[:len(ciphertext)-o.tagSize]
+	ret, out := sliceForAppend(dst, len(ciphertext))
+	var expected = o.crypt(out, nonce, ciphertext, additionalData, false)
+	if subtle.ConstantTimeCompare(expected[:o.tagSize], tag) != 1 {
+		clear(out)
+		return nil, errOpen
+	}
+	return ret, nil
+}
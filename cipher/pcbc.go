@@ -0,0 +1,78 @@
+package cipher
+
+import (
+	"crypto/cipher"
+	"crypto/subtle"
+)
+
+// pcbc is the Propagating CBC mode of Kerberos v4:
+//
+//	c[i] = E(p[i] ^ p[i-1] ^ c[i-1])
+//
+// with p[0] ^ c[0] being the IV.
+type pcbc struct {
+	b         cipher.Block
+	blockSize int
+	v         []byte
+}
+
+func newPCBC(b cipher.Block, iv []byte) *pcbc {
+	if len(iv) != b.BlockSize() {
+		panic("crypt/cipher: IV length must equal block size")
+	}
+	return &pcbc{b: b, blockSize: b.BlockSize(), v: append([]byte{}, iv...)}
+}
+
+type pcbcEncrypter pcbc
+
+// NewPCBCEncrypter returns a BlockMode which encrypts in PCBC mode with b.
+func NewPCBCEncrypter(b cipher.Block, iv []byte) cipher.BlockMode {
+	return (*pcbcEncrypter)(newPCBC(b, iv))
+}
+
+func (x *pcbcEncrypter) BlockSize() int { return x.blockSize }
+
+func (x *pcbcEncrypter) CryptBlocks(dst, src []byte) {
+	if len(src)%x.blockSize != 0 {
+		panic("crypt/cipher: input not full blocks")
+	}
+	if len(dst) < len(src) {
+		panic("crypt/cipher: output smaller than input")
+	}
+	var block = make([]byte, x.blockSize)
+	for len(src) > 0 {
+		subtle.XORBytes(block, src[:x.blockSize], x.v)
+		x.b.Encrypt(block, block)
+		subtle.XORBytes(x.v, src[:x.blockSize], block)
+		copy(dst, block)
+		src = src[x.blockSize:]
+		dst = dst[x.blockSize:]
+	}
+}
+
+type pcbcDecrypter pcbc
+
+// NewPCBCDecrypter returns a BlockMode which decrypts in PCBC mode with b.
+func NewPCBCDecrypter(b cipher.Block, iv []byte) cipher.BlockMode {
+	return (*pcbcDecrypter)(newPCBC(b, iv))
+}
+
+func (x *pcbcDecrypter) BlockSize() int { return x.blockSize }
+
+func (x *pcbcDecrypter) CryptBlocks(dst, src []byte) {
+	if len(src)%x.blockSize != 0 {
+		panic("crypt/cipher: input not full blocks")
+	}
+	if len(dst) < len(src) {
+		panic("crypt/cipher: output smaller than input")
+	}
+	var block = make([]byte, x.blockSize)
+	for len(src) > 0 {
+		x.b.Decrypt(block, src[:x.blockSize])
+		subtle.XORBytes(block, block, x.v)
+		subtle.XORBytes(x.v, src[:x.blockSize], block)
+		copy(dst, block)
+		src = src[x.blockSize:]
+		dst = dst[x.blockSize:]
+	}
+}
@@ -0,0 +1,41 @@
+package cipher
+
+import (
+	"bytes"
+	"crypto/des"
+	"encoding/hex"
+	"testing"
+)
+
+func TestPCBC(t *testing.T) {
+	// OpenSSL destest.c pcbc_ok, DES_pcbc_encrypt of cbc_data zero padded to
+	// 32 bytes
+	var key, _ = hex.DecodeString("0123456789abcdef")
+	var iv, _ = hex.DecodeString("fedcba9876543210")
+	var plaintext = []byte("7654321 Now is the time for \x00\x00\x00\x00")
+	block, err := des.NewCipher(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// one block at a time must chain like a single call
+	var ciphertext = make([]byte, len(plaintext))
+	var enc = NewPCBCEncrypter(block, iv)
+	for i := 0; i < len(plaintext); i += 8 {
+		enc.CryptBlocks(ciphertext[i:i+8], plaintext[i:i+8])
+	}
+	if hex.EncodeToString(ciphertext) != "ccd173ffab2039f46decb470a0e56b15aea6bf61ed7d9c9ff717463b8ab3cc88" {
+		t.Fatalf("%x", ciphertext)
+	}
+	// in place
+	NewPCBCDecrypter(block, iv).CryptBlocks(ciphertext, ciphertext)
+	if !bytes.Equal(ciphertext, plaintext) {
+		t.Fatalf("decrypt %q", ciphertext)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("PCBC accepted a short IV")
+		}
+	}()
+	NewPCBCEncrypter(block, iv[:4])
+}
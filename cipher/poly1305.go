@@ -0,0 +1,127 @@
+package cipher
+
+import (
+	"encoding/binary"
+	"errors"
+	"hash"
+	"math/bits"
+)
+
+var errPoly1305Key = errors.New("crypt/cipher: Poly1305 key must be 32 bytes")
+
+// poly1305 is Poly1305 of RFC 8439 with h in three 64-bit limbs. Unlike
+// golang.org/x/crypto/poly1305 all state is held by value, so Sum works on a
+// copy and writing may go on after it.
+type poly1305 struct {
+	r   [2]uint64
+	s   [2]uint64
+	h   [3]uint64
+	buf [16]byte
+	n   int
+}
+
+// NewPoly1305 returns Poly1305 (RFC 8439) with a 32 byte one time key. Sum does
+// not change the state, but the key must never authenticate two messages.
+func NewPoly1305(key []byte) (hash.Hash, error) {
+	if len(key) != 32 {
+		return nil, errPoly1305Key
+	}
+	var p = new(poly1305)
+	// clamp r
+	p.r[0] = binary.LittleEndian.Uint64(key) & 0x0ffffffc0fffffff
+	p.r[1] = binary.LittleEndian.Uint64(key[8:]) & 0x0ffffffc0ffffffc
+	p.s[0] = binary.LittleEndian.Uint64(key[16:])
+	p.s[1] = binary.LittleEndian.Uint64(key[24:])
+	return p, nil
+}
+
+func (p *poly1305) Size() int      { return 16 }
+func (p *poly1305) BlockSize() int { return 16 }
+
+func (p *poly1305) Reset() {
+	p.h = [3]uint64{}
+	p.n = 0
+}
+
+func (p *poly1305) Write(b []byte) (int, error) {
+	var n = len(b)
+	if p.n > 0 {
+		c := copy(p.buf[p.n:], b)
+		p.n += c
+		b = b[c:]
+		if p.n < 16 {
+			return n, nil
+		}
+		p.block(p.buf[:], 1)
+		p.n = 0
+	}
+	for ; len(b) >= 16; b = b[16:] {
+		p.block(b, 1)
+	}
+	p.n = copy(p.buf[:], b)
+	return n, nil
+}
+
+func (p *poly1305) Sum(in []byte) []byte {
+	var s = *p
+	if s.n > 0 {
+		// the partial block is padded with a 1 byte instead of the 2^128 bit
+		var last [16]byte
+		copy(last[:], s.buf[:s.n])
+		last[s.n] = 1
+		s.block(last[:], 0)
+	}
+	// h - p, kept if it does not borrow, that is if h >= 2^130 - 5
+	h0, b := bits.Sub64(s.h[0], 0xfffffffffffffffb, 0)
+	h1, b := bits.Sub64(s.h[1], 0xffffffffffffffff, b)
+	_, b = bits.Sub64(s.h[2], 3, b)
+	var mask = b - 1
+	h0 = s.h[0]&^mask | h0&mask
+	h1 = s.h[1]&^mask | h1&mask
+	// tag = h + s mod 2^128
+	h0, c := bits.Add64(h0, s.s[0], 0)
+	h1, _ = bits.Add64(h1, s.s[1], c)
+	var tag [16]byte
+	binary.LittleEndian.PutUint64(tag[:], h0)
+	binary.LittleEndian.PutUint64(tag[8:], h1)
+	return append(in, tag[:]...)
+}
+
+// block sets h to (h + m + hibit * 2^128) * r mod 2^130 - 5.
+func (p *poly1305) block(m []byte, hibit uint64) {
+	var h0, h1, h2 = p.h[0], p.h[1], p.h[2]
+	var c uint64
+	h0, c = bits.Add64(h0, binary.LittleEndian.Uint64(m), 0)
+	h1, c = bits.Add64(h1, binary.LittleEndian.Uint64(m[8:]), c)
+	h2 += c + hibit
+
+	// h2 is at most 7 and r is clamped, so h2 * r fits in 64 bits
+	var r0, r1 = p.r[0], p.r[1]
+	m0hi, m0lo := bits.Mul64(h0, r0)
+	h1r0hi, h1r0lo := bits.Mul64(h1, r0)
+	h0r1hi, h0r1lo := bits.Mul64(h0, r1)
+	h1r1hi, h1r1lo := bits.Mul64(h1, r1)
+	var h2r0, h2r1 = h2 * r0, h2 * r1
+
+	m1lo, c := bits.Add64(h1r0lo, h0r1lo, 0)
+	m1hi, _ := bits.Add64(h1r0hi, h0r1hi, c)
+	m2lo, c := bits.Add64(h2r0, h1r1lo, 0)
+	m2hi, _ := bits.Add64(0, h1r1hi, c)
+
+	var t0 = m0lo
+	t1, c := bits.Add64(m1lo, m0hi, 0)
+	t2, c := bits.Add64(m2lo, m1hi, c)
+	t3, _ := bits.Add64(h2r1, m2hi, c)
+
+	// 2^130 = 5 mod p: add t >> 130 times 4 and times 1
+	h0, h1, h2 = t0, t1, t2&3
+	var cc0, cc1 = t2 &^ 3, t3
+	h0, c = bits.Add64(h0, cc0, 0)
+	h1, c = bits.Add64(h1, cc1, c)
+	h2 += c
+	cc0, cc1 = cc0>>2|cc1<<62, cc1>>2
+	h0, c = bits.Add64(h0, cc0, 0)
+	h1, c = bits.Add64(h1, cc1, c)
+	h2 += c
+	p.h = [3]uint64{h0, h1, h2}
+}
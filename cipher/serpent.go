@@ -0,0 +1,175 @@
+package cipher
+
+import (
+	"crypto/cipher"
+	"encoding/binary"
+	"errors"
+	"math/bits"
+)
+
+var errSerpentKeySize = errors.New("crypt/cipher: Serpent key must be 16, 24 or 32 bytes")
+
+// serpentPhi is the fractional part of the golden ratio, used by the key
+// schedule.
+const serpentPhi = 0x9e3779b9
+
+// serpentSbox are the eight 4 bit S-boxes of Serpent.
+var serpentSbox = [8][16]byte{
+	{3, 8, 15, 1, 10, 6, 5, 11, 14, 13, 4, 2, 7, 0, 9, 12},
+	{15, 12, 2, 7, 9, 0, 5, 10, 1, 11, 14, 8, 6, 13, 3, 4},
+	{8, 6, 7, 9, 3, 12, 10, 15, 13, 1, 14, 4, 0, 11, 5, 2},
+	{0, 15, 11, 8, 12, 9, 6, 3, 13, 1, 2, 4, 10, 7, 5, 14},
+	{1, 15, 8, 3, 12, 0, 11, 6, 2, 5, 4, 10, 9, 14, 7, 13},
+	{15, 5, 2, 11, 4, 10, 9, 12, 0, 3, 14, 8, 13, 6, 7, 1},
+	{7, 2, 12, 5, 8, 4, 6, 11, 14, 9, 1, 15, 13, 3, 10, 0},
+	{1, 13, 15, 0, 14, 8, 2, 11, 7, 4, 12, 10, 9, 3, 5, 6},
+}
+
+// serpentSboxInv are the inverses of serpentSbox.
+var serpentSboxInv [8][16]byte
+
+func init() {
+	for i, s := range serpentSbox {
+		for x, y := range s {
+			serpentSboxInv[i][y] = byte(x)
+		}
+	}
+}
+
+// serpent is the 32 round Serpent block cipher with 33 128 bit subkeys. It
+// uses the byte order of the NESSIE test vectors, as libgcrypt and Linux do.
+type serpent struct {
+	k [33][4]uint32
+}
+
+// NewSerpent returns the Serpent block cipher with a 16, 24 or 32 byte key.
+func NewSerpent(key []byte) (cipher.Block, error) {
+	switch len(key) {
+	case 16, 24, 32:
+	default:
+		return nil, errSerpentKeySize
+	}
+	// short keys are padded to 256 bits with a single 1 bit
+	var padded [32]byte
+	copy(padded[:], key)
+	if len(key) < 32 {
+		padded[len(key)] = 1
+	}
+	var w [140]uint32
+	for i := 0; i < 8; i++ {
+		w[i] = binary.LittleEndian.Uint32(padded[4*i:])
+	}
+	for i := 8; i < 140; i++ {
+		w[i] = bits.RotateLeft32(w[i-8]^w[i-5]^w[i-3]^w[i-1]^serpentPhi^uint32(i-8), 11)
+	}
+	var s = new(serpent)
+	for i := range s.k {
+		var k = [4]uint32{w[8+4*i], w[9+4*i], w[10+4*i], w[11+4*i]}
+		serpentSubst(&k, &serpentSbox[(35-i)%8])
+		s.k[i] = k
+	}
+	return s, nil
+}
+
+func (s *serpent) BlockSize() int { return 16 }
+
+func (s *serpent) Encrypt(dst, src []byte) {
+	if len(src) < 16 {
+		panic("crypt/cipher: input not full block")
+	}
+	if len(dst) < 16 {
+		panic("crypt/cipher: output not full block")
+	}
+	var x = serpentLoad(src)
+	for i := 0; i < 32; i++ {
+		serpentXor(&x, &s.k[i])
+		serpentSubst(&x, &serpentSbox[i%8])
+		if i < 31 {
+			serpentLT(&x)
+		}
+	}
+	serpentXor(&x, &s.k[32])
+	serpentStore(dst, &x)
+}
+
+func (s *serpent) Decrypt(dst, src []byte) {
+	if len(src) < 16 {
+		panic("crypt/cipher: input not full block")
+	}
+	if len(dst) < 16 {
+		panic("crypt/cipher: output not full block")
+	}
+	var x = serpentLoad(src)
+	serpentXor(&x, &s.k[32])
+	for i := 31; i >= 0; i-- {
+		if i < 31 {
+			serpentLTInv(&x)
+		}
+		serpentSubst(&x, &serpentSboxInv[i%8])
+		serpentXor(&x, &s.k[i])
+	}
+	serpentStore(dst, &x)
+}
+
+func serpentLoad(src []byte) [4]uint32 {
+	return [4]uint32{
+		binary.LittleEndian.Uint32(src),
+		binary.LittleEndian.Uint32(src[4:]),
+		binary.LittleEndian.Uint32(src[8:]),
+		binary.LittleEndian.Uint32(src[12:]),
+	}
+}
+
+func serpentStore(dst []byte, x *[4]uint32) {
+	for i, v := range x {
+		binary.LittleEndian.PutUint32(dst[4*i:], v)
+	}
+}
+
+func serpentXor(x, k *[4]uint32) {
+	for i := range x {
+		x[i] ^= k[i]
+	}
+}
+
+// serpentSubst applies the S-box to the 32 columns of x, bit j of x[0] is
+// the lowest input bit of column j.
+func serpentSubst(x *[4]uint32, sbox *[16]byte) {
+	var y [4]uint32
+	for j := 0; j < 32; j++ {
+		var in = (x[0]>>j)&1 | (x[1]>>j&1)<<1 | (x[2]>>j&1)<<2 | (x[3]>>j&1)<<3
+		var out = uint32(sbox[in])
+		y[0] |= (out & 1) << j
+		y[1] |= (out >> 1 & 1) << j
+		y[2] |= (out >> 2 & 1) << j
+		y[3] |= (out >> 3 & 1) << j
+	}
+	*x = y
+}
+
+// serpentLT is the linear transformation between rounds.
+func serpentLT(x *[4]uint32) {
+	x[0] = bits.RotateLeft32(x[0], 13)
+	x[2] = bits.RotateLeft32(x[2], 3)
+	x[1] ^= x[0] ^ x[2]
+	x[3] ^= x[2] ^ x[0]<<3
+	x[1] = bits.RotateLeft32(x[1], 1)
+	x[3] = bits.RotateLeft32(x[3], 7)
+	x[0] ^= x[1] ^ x[3]
+	x[2] ^= x[3] ^ x[1]<<7
+	x[0] = bits.RotateLeft32(x[0], 5)
+	x[2] = bits.RotateLeft32(x[2], 22)
+}
+
+func serpentLTInv(x *[4]uint32) {
+	x[2] = bits.RotateLeft32(x[2], -22)
+	x[0] = bits.RotateLeft32(x[0], -5)
+	x[2] ^= x[3] ^ x[1]<<7
+	x[0] ^= x[1] ^ x[3]
+	x[3] = bits.RotateLeft32(x[3], -7)
+	x[1] = bits.RotateLeft32(x[1], -1)
+	x[3] ^= x[2] ^ x[0]<<3
+	x[1] ^= x[0] ^ x[2]
+	x[2] = bits.RotateLeft32(x[2], -3)
+	x[0] = bits.RotateLeft32(x[0], -13)
+}
@@ -0,0 +1,116 @@
+package cipher
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/subtle"
+	"errors"
+)
+
+// SIVMaxComponents is the largest number of associated data components,
+// including the nonce, SIV can authenticate.
+const SIVMaxComponents = 126
+
+var (
+	errSIVKeySize    = errors.New("crypt/cipher: SIV key must be 32, 48 or 64 bytes")
+	errSIVComponents = errors.New("crypt/cipher: too many SIV associated data components")
+)
+
+// SIV is the deterministic authenticated encryption mode AES-SIV of RFC 5297.
+// The same plaintext and associated data always give the same ciphertext, the
+// synthetic IV in front of it is also the tag.
+type SIV struct {
+	mac cipher.Block
+	ctr cipher.Block
+}
+
+// NewSIV returns AES-SIV with key, 32, 48 or 64 bytes. The first half of the
+// key is the CMAC key, the second half the CTR key.
+func NewSIV(key []byte) (*SIV, error) {
+	switch len(key) {
+	case 32, 48, 64:
+	default:
+		return nil, errSIVKeySize
+	}
+	mac, err := aes.NewCipher(key[:len(key)/2])
+	if err != nil {
+		return nil, err
+	}
+	ctr, err := aes.NewCipher(key[len(key)/2:])
+	if err != nil {
+		return nil, err
+	}
+	return &SIV{mac: mac, ctr: ctr}, nil
+}
+
+// Overhead returns the size of the synthetic IV.
+func (s *SIV) Overhead() int { return aes.BlockSize }
+
+// Seal encrypts and authenticates plaintext with the associated data
+// components and appends the result to dst. A nonce, if used, is passed as the
+// last component.
+func (s *SIV) Seal(dst, plaintext []byte, additionalData ...[]byte) ([]byte, error) {
+	if len(additionalData) > SIVMaxComponents {
+		return nil, errSIVComponents
+	}
+	var v = s.s2v(additionalData, plaintext)
+	ret, out := sliceForAppend(dst, aes.BlockSize+len(plaintext))
+	copy(out, v)
+	s.xor(v, out[aes.BlockSize:], plaintext)
+	return ret, nil
+}
+
+// Open decrypts and authenticates a ciphertext produced by Seal with the same
+// associated data components and appends the plaintext to dst.
+func (s *SIV) Open(dst, ciphertext []byte, additionalData ...[]byte) ([]byte, error) {
+	if len(additionalData) > SIVMaxComponents {
+		return nil, errSIVComponents
+	}
+	if len(ciphertext) < aes.BlockSize {
+		return nil, errOpen
+	}
+	var v = ciphertext[:aes.BlockSize]
+	ciphertext = ciphertext[aes.BlockSize:]
+	ret, out := sliceForAppend(dst, len(ciphertext))
+	s.xor(v, out, ciphertext)
+	if subtle.ConstantTimeCompare(s.s2v(additionalData, out), v) != 1 {
+		clear(out)
+		return nil, errOpen
+	}
+	return ret, nil
+}
+
+// xor is AES-CTR starting at the synthetic IV with bits 31 and 63 cleared.
+func (s *SIV) xor(v, dst, src []byte) {
+	var q = append([]byte{}, v...)
+	q[8] &= 0x7f
+	q[12] &= 0x7f
+	cipher.NewCTR(s.ctr, q).XORKeyStream(dst, src)
+}
+
+// s2v is the S2V function of RFC 5297 section 2.4 over the components and
+// the plaintext, which is the last string.
+func (s *SIV) s2v(components [][]byte, plaintext []byte) []byte {
+	var d = s.cmac(make([]byte, aes.BlockSize))
+	for _, c := range components {
+		d = shiftLeft(d, 0x87)
+		subtle.XORBytes(d, d, s.cmac(c))
+	}
+	var t []byte
+	if len(plaintext) >= aes.BlockSize {
+		t = append([]byte{}, plaintext...)
+		subtle.XORBytes(t[len(t)-aes.BlockSize:], t[len(t)-aes.BlockSize:], d)
+	} else {
+		t = make([]byte, aes.BlockSize)
+		copy(t, plaintext)
+		t[len(plaintext)] = 0x80
+		subtle.XORBytes(t, t, shiftLeft(d, 0x87))
+	}
+	return s.cmac(t)
+}
+
+func (s *SIV) cmac(p []byte) []byte {
+	m, _ := NewCMAC(s.mac)
+	m.Write(p)
+	return m.Sum(nil)
+}
@@ -0,0 +1,127 @@
+package cipher
+
+import (
+	"crypto/cipher"
+	"crypto/subtle"
+	"encoding/binary"
+	"errors"
+)
+
+var errXTSBlockSize = errors.New("crypt/cipher: XTS requires a 128-bit block cipher")
+
+// XTS is the XEX-based tweaked codebook mode with ciphertext stealing of IEEE
+// 1619 and NIST SP 800-38E. Each data unit, such as a disk sector, is
+// encrypted with a tweak derived from its number, and may end in a partial
+// block.
+type XTS struct {
+	k1, k2 cipher.Block
+}
+
+// NewXTS returns XTS with k1 encrypting the data and k2 the tweak, the two
+// halves of the XTS key.
+func NewXTS(k1, k2 cipher.Block) (*XTS, error) {
+	if k1.BlockSize() != 16 || k2.BlockSize() != 16 {
+		return nil, errXTSBlockSize
+	}
+	return &XTS{k1: k1, k2: k2}, nil
+}
+
+// Encrypt encrypts the data unit src, at least one block, into dst with the
+// sector number as the tweak. dst and src may overlap entirely.
+func (x *XTS) Encrypt(dst, src []byte, sector uint64) {
+	x.EncryptTweak(dst, src, sectorTweak(sector))
+}
+
+// Decrypt decrypts the data unit src into dst, see Encrypt.
+func (x *XTS) Decrypt(dst, src []byte, sector uint64) {
+	x.DecryptTweak(dst, src, sectorTweak(sector))
+}
+
+// EncryptTweak is like Encrypt with the 16 byte tweak given as is, as OpenSSL
+// takes it for the IV.
+func (x *XTS) EncryptTweak(dst, src, tweak []byte) {
+	x.crypt(dst, src, tweak, true)
+}
+
+// DecryptTweak is like Decrypt with the 16 byte tweak given as is.
+func (x *XTS) DecryptTweak(dst, src, tweak []byte) {
+	x.crypt(dst, src, tweak, false)
+}
+
+// sectorTweak encodes the sector number little endian, as IEEE 1619 does.
+func sectorTweak(sector uint64) []byte {
+	var tweak = make([]byte, 16)
+	binary.LittleEndian.PutUint64(tweak, sector)
+	return tweak
+}
+
+func (x *XTS) crypt(dst, src, tweak []byte, encrypt bool) {
+	if len(src) < 16 {
+		panic("crypt/cipher: XTS input shorter than a block")
+	}
+	if len(dst) < len(src) {
+		panic("crypt/cipher: output smaller than input")
+	}
+	if len(tweak) != 16 {
+		panic("crypt/cipher: incorrect tweak length given to XTS")
+	}
+	var t = make([]byte, 16)
+	x.k2.Encrypt(t, tweak)
+	var full = len(src) / 16
+	var tail = len(src) % 16
+	if tail > 0 {
+		// the last full block is combined with the partial one
+		full--
+	}
+	for i := 0; i < full; i++ {
+		x.block(dst[i*16:i*16+16], src[i*16:i*16+16], t, encrypt)
+		mulAlpha(t)
+	}
+	if tail == 0 {
+		return
+	}
+	var last = full * 16
+	var block = make([]byte, 16)
+	if encrypt {
+		x.block(block, src[last:last+16], t, true)
+		mulAlpha(t)
+		var stolen = make([]byte, 16)
+		copy(stolen, src[last+16:])
+		copy(stolen[tail:], block[tail:])
+		copy(dst[last+16:], block[:tail])
+		x.block(dst[last:last+16], stolen, t, true)
+		return
+	}
+	// the partial block was encrypted with the tweak after the last full one
+	var next = append([]byte{}, t...)
+	mulAlpha(next)
+	x.block(block, src[last:last+16], next, false)
+	var stolen = make([]byte, 16)
+	copy(stolen, src[last+16:])
+	copy(stolen[tail:], block[tail:])
+	copy(dst[last+16:], block[:tail])
+	x.block(dst[last:last+16], stolen, t, false)
+}
+
+// block is XEX of one block with the encrypted tweak t.
+func (x *XTS) block(dst, src, t []byte, encrypt bool) {
+	subtle.XORBytes(dst, src, t)
+	if encrypt {
+		x.k1.Encrypt(dst, dst)
+	} else {
+		x.k1.Decrypt(dst, dst)
+	}
+	subtle.XORBytes(dst, dst, t)
+}
+
+// mulAlpha multiplies the tweak by the primitive element of GF(2^128) in the
+// little endian byte order of IEEE 1619.
+func mulAlpha(t []byte) {
+	var carry byte
+	for i := range t {
+		next := t[i] >> 7
+		t[i] = t[i]<<1 | carry
+		carry = next
+	}
+	t[0] ^= byte(subtle.ConstantTimeByteEq(carry, 1)) * 0x87
+}
@@ -0,0 +1,110 @@
+package crypt
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+)
+
+func TestBlockCipherVectors(t *testing.T) {
+	var vectors = []struct {
+		method                     CipherMethod
+		key, plaintext, ciphertext string
+	}{
+		// Twofish paper, ECB_TBL.TXT
+		{METHOD_TWOFISH, "00000000000000000000000000000000", "00000000000000000000000000000000", "9f589f5cf6122c32b6bfec2f2ae8c35a"},
+		{METHOD_TWOFISH, "0000000000000000000000000000000000000000000000000000000000000000", "00000000000000000000000000000000", "57ff739d4dc92c1bd7fc01700cc8216f"},
+		// RFC 2144 B.1
+		{METHOD_CAST5, "0123456712345678234567893456789a", "0123456789abcdef", "238b4fe5847e44b2"},
+		// RFC 3713 appendix A
+		{METHOD_CAMELLIA, "0123456789abcdeffedcba9876543210", "0123456789abcdeffedcba9876543210", "67673138549669730857065648eabe43"},
+		{METHOD_CAMELLIA, "0123456789abcdeffedcba98765432100011223344556677", "0123456789abcdeffedcba9876543210", "b4993401b3e996f84ee5cee7d79b09b9"},
+		{METHOD_CAMELLIA, "0123456789abcdeffedcba987654321000112233445566778899aabbccddeeff", "0123456789abcdeffedcba9876543210", "9acc237dff16d76c20ef7c919e3a7509"},
+		// GB/T 32907-2016 appendix A.1
+		{METHOD_SM4, "0123456789abcdeffedcba9876543210", "0123456789abcdeffedcba9876543210", "681edf34d206965e86b3e94f536e4246"},
+		// NESSIE set 1 vector 0 and set 2 vector 0, then checked with libgcrypt
+		{METHOD_SERPENT, "80000000000000000000000000000000", "00000000000000000000000000000000", "264e5481eff42a4606abda06c0bfda3d"},
+		{METHOD_SERPENT, "00000000000000000000000000000000", "00000000000000000000000000000000", "3620b17ae6a993d09618b8768266bae9"},
+		{METHOD_SERPENT, "000102030405060708090a0b0c0d0e0f1011121314151617", "00112233445566778899aabbccddeeff", "6ab816c82de53b93005008afa2246a02"},
+		{METHOD_SERPENT, "000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f", "00112233445566778899aabbccddeeff", "2868b7a2d28ecd5e4fdefac3c4330074"},
+		{METHOD_XTEA, "000102030405060708090a0b0c0d0e0f", "4142434445464748", "497df3d072612cb5"},
+	}
+	var opts = Options{Mode: MODE_ECB, Padding: PAD_NOPADDING}
+	for i, v := range vectors {
+		key, _ := hex.DecodeString(v.key)
+		plaintext, _ := hex.DecodeString(v.plaintext)
+		c, err := New(v.method, key, nil, opts)
+		if err != nil {
+			t.Fatal(i, v.method, err)
+		}
+		ciphertext, err := c.Encrypt(plaintext)
+		if err != nil {
+			t.Fatal(i, v.method, err)
+		}
+		if hex.EncodeToString(ciphertext) != v.ciphertext {
+			t.Fatalf("%d %s: Encrypt %x", i, v.method, ciphertext)
+		}
+		if decrypted, err := c.Decrypt(ciphertext); err != nil || !bytes.Equal(decrypted, plaintext) {
+			t.Fatalf("%d %s: Decrypt %x, %v", i, v.method, decrypted, err)
+		}
+	}
+}
+
+func TestBlockCipherMethods(t *testing.T) {
+	var text = []byte("additional block ciphers")
+	var methods = []struct {
+		method  CipherMethod
+		new     func(key, iv []byte, args ...Options) (*Crypt, error)
+		keySize int
+	}{
+		{METHOD_TWOFISH, NewTwofish, 32},
+		{METHOD_CAST5, NewCAST5, 16},
+		{METHOD_CAMELLIA, NewCamellia, 24},
+		{METHOD_SM4, NewSM4, 16},
+		{METHOD_SERPENT, NewSerpent, 16},
+		{METHOD_XTEA, NewXTEA, 16},
+	}
+	for _, m := range methods {
+		var key = randBytes(m.keySize)
+		block, err := newBlockCipher(m.method, key)
+		if err != nil {
+			t.Fatal(m.method, err)
+		}
+		for _, mode := range []BlockMode{MODE_CBC, MODE_CFB, MODE_CTR, MODE_OFB, MODE_ECB, MODE_CBC_CTS, MODE_IGE, MODE_PCBC} {
+			var iv = randBytes(derivedIVSize(block.BlockSize(), mode))
+			if mode == MODE_ECB {
+				iv = nil
+			}
+			c, err := m.new(key, iv, Options{Mode: mode, Padding: PAD_ISO10126})
+			if err != nil {
+				t.Fatal(m.method, mode, err)
+			}
+			ciphertext, err := c.Encrypt(text)
+			if err != nil {
+				t.Fatal(m.method, mode, err)
+			}
+			if plaintext, err := c.Decrypt(ciphertext); err != nil || !bytes.Equal(plaintext, text) {
+				t.Fatal(m.method, mode, "decrypt failed", err)
+			}
+		}
+
+		// salted header with a password of any length
+		c, err := m.new(testPassword, nil)
+		if err != nil {
+			t.Fatal(m.method, err)
+		}
+		ciphertext, err := c.Encrypt(text)
+		if err != nil {
+			t.Fatal(m.method, err)
+		}
+		if !bytes.HasPrefix(ciphertext, []byte(saltedText)) {
+			t.Fatal(m.method, "no salted header")
+		}
+		if plaintext, err := c.Decrypt(ciphertext); err != nil || !bytes.Equal(plaintext, text) {
+			t.Fatal(m.method, "password decrypt failed", err)
+		}
+		if _, err = m.new(key, nil, Options{Mode: MODE_GCM}); err == nil {
+			t.Fatal(m.method, "expected an error for an AEAD mode")
+		}
+	}
+}
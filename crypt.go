@@ -2,12 +2,15 @@ package crypt
 
 import (
 	"bytes"
+	"crypto"
 	"crypto/aes"
 	"crypto/cipher"
-	"crypto/des"
+	"crypto/subtle"
 	"fmt"
+	"math"
+	"sort"
 
-	"golang.org/x/crypto/blowfish"
+	ciphers "github.com/kayon/crypt/cipher"
 )
 
 const (
@@ -25,6 +28,16 @@ const (
 	METHOD_CHACHA20
 	METHOD_BLOWFISH
 	METHOD_RC4
+	METHOD_CHACHA20POLY1305
+	METHOD_XCHACHA20POLY1305
+	METHOD_TWOFISH
+	METHOD_CAST5
+	METHOD_CAMELLIA
+	METHOD_SM4
+	METHOD_SERPENT
+	METHOD_XTEA
+	METHOD_SALSA20
+	METHOD_XSALSA20
 )
 
 func (method CipherMethod) String() string {
@@ -41,6 +54,29 @@ func (method CipherMethod) String() string {
 		return "Blowfish"
 	case METHOD_RC4:
 		return "RC4"
+	case METHOD_CHACHA20POLY1305:
+		return "ChaCha20-Poly1305"
+	case METHOD_XCHACHA20POLY1305:
+		return "XChaCha20-Poly1305"
+	case METHOD_TWOFISH:
+		return "Twofish"
+	case METHOD_CAST5:
+		return "CAST5"
+	case METHOD_CAMELLIA:
+		return "Camellia"
+	case METHOD_SM4:
+		return "SM4"
+	case METHOD_SERPENT:
+		return "Serpent"
+	case METHOD_XTEA:
+		return "XTEA"
+	case METHOD_SALSA20:
+		return "Salsa20"
+	case METHOD_XSALSA20:
+		return "XSalsa20"
+	}
+	if b := blockCipherOf(method); b != nil {
+		return b.name
 	}
 	return ""
 }
@@ -48,6 +84,35 @@ func (method CipherMethod) String() string {
 type Options struct {
 	Mode    BlockMode
 	Padding PaddingScheme
+	// AAD is additional data authenticated but not encrypted by AEAD modes.
+	AAD []byte
+	// KDF derives the key and IV from the password when no IV is given. If nil
+	// the legacy salted header with EVP_BytesToKey (MD5) is written. Decrypt
+	// reads either header without it. Only with a KDF may the key be a
+	// password of any length, otherwise it must be a key size of the method.
+	KDF KDF
+	// MAC appends an encrypt-then-MAC tag over the IV, header and ciphertext
+	// in the unauthenticated modes. Decrypt verifies it before unpadding.
+	MAC MACAlgorithm
+	// NonceSize is the nonce size of the AES AEAD modes. By default it is the
+	// size of the given nonce, or 12 bytes (16 for MODE_EAX) when it is
+	// derived. MODE_GCM and MODE_GCMSIV only take 12 bytes, MODE_CCM 7 to 13,
+	// MODE_EAX 1 to 16 and MODE_OCB 1 to 15.
+	NonceSize int
+	// TagSize is the tag size of the AES AEAD modes, 16 bytes by default.
+	// MODE_GCM takes 12 to 16 bytes, MODE_CCM, MODE_EAX and MODE_OCB 4 to 16
+	// (even for MODE_CCM), MODE_GCMSIV only 16.
+	TagSize int
+	// CTS is the ciphertext stealing variant of MODE_CBC_CTS.
+	CTS CTSVariant
+	// Legacy selects the Blowfish format from before NewBlowfish took an IV
+	// and Options: ECB, zero padding only for a partial last block and no
+	// salted header. Mode and Padding are ignored and no IV may be given.
+	Legacy bool
+	// Counter is the initial ChaCha20 block counter, 0 by default. RFC 8439
+	// encrypts from 1, block 0 keys Poly1305. With a 12 byte nonce it must
+	// fit in 32 bits.
+	Counter uint64
 }
 
 func NewAES(key, iv []byte, args ...Options) (*Crypt, error) {
@@ -62,137 +127,408 @@ func NewDES3(key, iv []byte, args ...Options) (*Crypt, error) {
 	return newCrypt(METHOD_DES3, key, iv, args...)
 }
 
-func NewChaCha20(key, iv []byte) (*Crypt, error) {
-	return newCrypt(METHOD_CHACHA20, key, iv)
+// NewChaCha20 creates a ChaCha20 Crypt. The nonce size selects the variant: 8
+// bytes is the original ChaCha20 with a 64 bit block counter, 12 bytes RFC
+// 8439 (IETF) with a 32 bit counter, which limits a message to 256 GiB, and 24
+// bytes XChaCha20 with a 64 bit counter. Options.Counter sets the first block
+// counter. Options.AAD is only used by the segmented stream, see
+// NewSealWriter.
+func NewChaCha20(key, iv []byte, args ...Options) (*Crypt, error) {
+	return newCrypt(METHOD_CHACHA20, key, iv, args...)
+}
+
+// NewSalsa20 creates a Salsa20 Crypt with a 32 byte key and an 8 byte nonce,
+// the same as libsodium crypto_stream_salsa20.
+func NewSalsa20(key, nonce []byte, args ...Options) (*Crypt, error) {
+	return newCrypt(METHOD_SALSA20, key, nonce, args...)
+}
+
+// NewXSalsa20 creates an XSalsa20 Crypt with a 32 byte key and a 24 byte
+// nonce, the same as libsodium crypto_stream_xsalsa20. See SecretBox for
+// XSalsa20-Poly1305.
+func NewXSalsa20(key, nonce []byte, args ...Options) (*Crypt, error) {
+	return newCrypt(METHOD_XSALSA20, key, nonce, args...)
+}
+
+func NewChaCha20Poly1305(key, nonce []byte, args ...Options) (*Crypt, error) {
+	return newCrypt(METHOD_CHACHA20POLY1305, key, nonce, args...)
 }
 
-func NewBlowfish(key []byte) (*Crypt, error) {
-	return newCrypt(METHOD_BLOWFISH, key, nil)
+func NewXChaCha20Poly1305(key, nonce []byte, args ...Options) (*Crypt, error) {
+	return newCrypt(METHOD_XCHACHA20POLY1305, key, nonce, args...)
+}
+
+// NewBlowfish creates a Blowfish Crypt with a key of 1 to 56 bytes. See
+// Options.Legacy for ciphertexts of earlier versions.
+func NewBlowfish(key, iv []byte, args ...Options) (*Crypt, error) {
+	return newCrypt(METHOD_BLOWFISH, key, iv, args...)
 }
 
 func NewRC4(key []byte) (*Crypt, error) {
 	return newCrypt(METHOD_RC4, key, nil)
 }
 
+// NewTwofish creates a Twofish Crypt with a 16, 24 or 32 byte key.
+func NewTwofish(key, iv []byte, args ...Options) (*Crypt, error) {
+	return newCrypt(METHOD_TWOFISH, key, iv, args...)
+}
+
+// NewCAST5 creates a CAST5 (CAST-128) Crypt with a 16 byte key, the OpenPGP
+// and OpenSSL cast5 key size.
+func NewCAST5(key, iv []byte, args ...Options) (*Crypt, error) {
+	return newCrypt(METHOD_CAST5, key, iv, args...)
+}
+
+// NewCamellia creates a Camellia Crypt with a 16, 24 or 32 byte key.
+func NewCamellia(key, iv []byte, args ...Options) (*Crypt, error) {
+	return newCrypt(METHOD_CAMELLIA, key, iv, args...)
+}
+
+// NewSM4 creates an SM4 Crypt with a 16 byte key.
+func NewSM4(key, iv []byte, args ...Options) (*Crypt, error) {
+	return newCrypt(METHOD_SM4, key, iv, args...)
+}
+
+// NewSerpent creates a Serpent Crypt with a 16, 24 or 32 byte key.
+func NewSerpent(key, iv []byte, args ...Options) (*Crypt, error) {
+	return newCrypt(METHOD_SERPENT, key, iv, args...)
+}
+
+// NewXTEA creates an XTEA Crypt with a 16 byte key.
+func NewXTEA(key, iv []byte, args ...Options) (*Crypt, error) {
+	return newCrypt(METHOD_XTEA, key, iv, args...)
+}
+
+// New creates a Crypt of any method, including the block ciphers added with
+// RegisterBlockCipher.
+func New(method CipherMethod, key, iv []byte, args ...Options) (*Crypt, error) {
+	return newCrypt(method, key, iv, args...)
+}
+
 func newCrypt(method CipherMethod, key, iv []byte, args ...Options) (*Crypt, error) {
-	var opts = Options{0, 0}
+	var opts = Options{}
 	if len(args) > 0 {
 		opts = args[0]
 	}
+	if opts.Legacy {
+		if method != METHOD_BLOWFISH {
+			return nil, fmt.Errorf("crypt %s: Options.Legacy is only for Blowfish", method)
+		} else if iv != nil {
+			return nil, fmt.Errorf("crypt %s: Options.Legacy takes no IV", method)
+		}
+		opts.Mode, opts.Padding = MODE_ECB, PAD_ZEROPADDING
+	}
+	if opts.Counter != 0 && method != METHOD_CHACHA20 {
+		return nil, fmt.Errorf("crypt %s: Options.Counter is only for ChaCha20", method)
+	}
 	var err error
-	if key, err = verifyKey(method, key); err != nil {
-		return nil, err
+	var password, saltKey = key, key
+	if key, err = verifyKey(method, opts.Mode, key); err != nil {
+		probe := Crypt{method: method, mode: opts.Mode, iv: iv}
+		if opts.KDF == nil || !probe.salted() || len(password) == 0 {
+			return nil, err
+		}
+		// with Options.KDF the key is a password, any length works since the
+		// cipher key is derived from it for every message
+		key, err = make([]byte, probe.saltKeyByteSize()), nil
+	} else {
+		saltKey = key
 	}
-	var block cipher.Block
+	var block, tweak cipher.Block
+	var nonceSize, tagSize int
 	switch method {
 	case METHOD_AES:
-		if block, err = aes.NewCipher(key); err == nil {
-			if opts.Mode.Not(MODE_ECB) && iv != nil {
-				if opts.Mode.Has(MODE_GCM) && len(iv) != gcmStandardNonceSize {
-					err = fmt.Errorf("crypt AES: incorrect nonce length given to GCM")
-				} else if opts.Mode.Not(MODE_GCM) && len(iv) != block.BlockSize() {
-					err = fmt.Errorf("crypt AES: IV length must equal block size (%d)", block.BlockSize())
+		if opts.Mode == MODE_SIV {
+			// the nonce is an associated data component of any size
+			if _, err = ciphers.NewSIV(key); err == nil {
+				tagSize = aes.BlockSize
+			}
+		} else if opts.Mode == MODE_XTS {
+			// the first half of the key encrypts the data, the second the tweak
+			if subtle.ConstantTimeCompare(key[:len(key)/2], key[len(key)/2:]) == 1 {
+				err = fmt.Errorf("crypt AES: XTS key halves must differ")
+			} else if block, err = aes.NewCipher(key[:len(key)/2]); err == nil {
+				if tweak, err = aes.NewCipher(key[len(key)/2:]); err == nil && iv != nil && len(iv) != aes.BlockSize {
+					err = nonceSizeError(method, len(iv), aes.BlockSize)
 				}
 			}
-		}
-	case METHOD_DES:
-		if opts.Mode == MODE_GCM {
-			err = fmt.Errorf("crypt DES: does not support MODE_GCM")
-		} else {
-			block, err = des.NewCipher(key)
-		}
-	case METHOD_DES3:
-		if opts.Mode == MODE_GCM {
-			err = fmt.Errorf("crypt DES3: does not support MODE_GCM")
-		} else {
-			block, err = des.NewTripleDESCipher(key)
+		} else if block, err = aes.NewCipher(key); err == nil {
+			if opts.Mode.aead() {
+				if nonceSize, tagSize, err = aeadSizes(opts.Mode, iv, opts); err == nil {
+					_, err = newAEAD(opts.Mode, key, block, nonceSize, tagSize)
+				}
+			} else if opts.Mode.Not(MODE_ECB) && iv != nil && len(iv) != derivedIVSize(block.BlockSize(), opts.Mode) {
+				err = nonceSizeError(method, len(iv), derivedIVSize(block.BlockSize(), opts.Mode))
+			}
 		}
 	case METHOD_CHACHA20:
 		if iv != nil {
 			switch len(iv) {
 			case 8, 12, 24:
 			default:
-				err = fmt.Errorf("crypt ChaCha20: invalid nonce size %d", len(iv))
+				err = nonceSizeError(method, len(iv), 8, 12, 24)
+			}
+			if err == nil && len(iv) == 12 && opts.Counter > math.MaxUint32 {
+				err = fmt.Errorf("crypt %s: Options.Counter %d does not fit the 32 bit counter of a 12 byte nonce", method, opts.Counter)
 			}
 		}
-	case METHOD_BLOWFISH:
-		block, err = blowfish.NewCipher(key)
+	case METHOD_SALSA20, METHOD_XSALSA20:
+		if iv != nil && len(iv) != salsa20NonceSize(method) {
+			err = nonceSizeError(method, len(iv), salsa20NonceSize(method))
+		}
+	case METHOD_CHACHA20POLY1305, METHOD_XCHACHA20POLY1305:
+		if iv != nil && len(iv) != chacha20Poly1305NonceSize(method) {
+			err = nonceSizeError(method, len(iv), chacha20Poly1305NonceSize(method))
+		}
 	case METHOD_RC4:
 
 	default:
-		return nil, fmt.Errorf("crypt unknown cipher method %d", method)
+		// DES, DES3, Blowfish and the registered block ciphers
+		var b = blockCipherOf(method)
+		if b == nil {
+			return nil, fmt.Errorf("crypt unknown cipher method %d", method)
+		}
+		if opts.Mode.aead() || opts.Mode.Has(MODE_SIV, MODE_XTS) {
+			err = fmt.Errorf("crypt %s: does not support %s mode", method, opts.Mode)
+		} else if block, err = b.newCipher(key); err == nil && opts.Mode.Not(MODE_ECB) && iv != nil && len(iv) != derivedIVSize(block.BlockSize(), opts.Mode) {
+			err = nonceSizeError(method, len(iv), derivedIVSize(block.BlockSize(), opts.Mode))
+		}
 	}
 	if err != nil {
 		return nil, err
 	}
+	if opts.CTS > CTS_CS3 {
+		return nil, fmt.Errorf("crypt %s: unknown CTS variant %d", method, opts.CTS)
+	}
+	if (opts.NonceSize != 0 || opts.TagSize != 0) && nonceSize == 0 {
+		return nil, fmt.Errorf("crypt %s: Options.NonceSize and Options.TagSize require an AES AEAD mode", method)
+	}
 
-	if !opts.Mode.Has(MODE_CBC, MODE_ECB) {
+	if !opts.Mode.padded() {
 		opts.Padding = PAD_NOPADDING
 	}
 
-	return &Crypt{
-		method:  method,
-		mode:    opts.Mode,
-		padding: opts.Padding,
-		block:   block,
-		key:     key,
-		iv:      iv,
-	}, nil
+	var c = &Crypt{
+		method:    method,
+		mode:      opts.Mode,
+		padding:   opts.Padding,
+		block:     block,
+		tweak:     tweak,
+		key:       key,
+		password:  password,
+		iv:        iv,
+		aad:       opts.AAD,
+		kdf:       opts.KDF,
+		mac:       opts.MAC,
+		nonceSize: nonceSize,
+		tagSize:   tagSize,
+		cts:       opts.CTS,
+		legacy:    opts.Legacy,
+		counter:   opts.Counter,
+	}
+	if c.salted() {
+		c.key = saltKey
+	}
+	if c.aad != nil && method != METHOD_CHACHA20 && !c.authenticated() {
+		return nil, fmt.Errorf("crypt %s: associated data requires an AEAD mode", method)
+	}
+	if c.mac != MAC_NONE {
+		if c.mac.Size() == 0 {
+			return nil, fmt.Errorf("crypt %s: unknown MAC %d", method, c.mac)
+		} else if c.authenticated() {
+			return nil, fmt.Errorf("crypt %s: Options.MAC is only for unauthenticated modes", method)
+		}
+	}
+	return c, nil
 }
 
 type Crypt struct {
-	method  CipherMethod
-	mode    BlockMode
-	padding PaddingScheme
-	block   cipher.Block
-	key     []byte
-	iv      []byte
+	method   CipherMethod
+	mode     BlockMode
+	padding  PaddingScheme
+	block    cipher.Block
+	tweak    cipher.Block // MODE_XTS
+	key      []byte
+	password []byte
+	iv       []byte
+	aad      []byte
+	kdf      KDF
+	mac      MACAlgorithm
+	// nonceSize and tagSize are set for the AES AEAD modes
+	nonceSize int
+	tagSize   int
+	cts       CTSVariant
+	legacy    bool   // Options.Legacy
+	counter   uint64 // Options.Counter
 }
 
 func (c Crypt) Encrypt(src []byte) ([]byte, error) {
+	return c.encrypt(src, c.messageAAD())
+}
+
+func (c Crypt) Decrypt(src []byte) ([]byte, error) {
+	return c.decrypt(src, c.messageAAD())
+}
+
+// EncryptWithAAD is like Encrypt but authenticates aad instead of Options.AAD.
+// The same aad must be given to DecryptWithAAD.
+func (c Crypt) EncryptWithAAD(src, aad []byte) ([]byte, error) {
+	return c.encrypt(src, aad)
+}
+
+// DecryptWithAAD is like Decrypt but authenticates aad instead of Options.AAD.
+// It returns an error wrapping ErrAuthentication if aad does not match.
+func (c Crypt) DecryptWithAAD(src, aad []byte) ([]byte, error) {
+	return c.decrypt(src, aad)
+}
+
+// messageAAD returns Options.AAD for Encrypt and the envelope. ChaCha20 has
+// no tag, its Options.AAD is only used by the segmented stream.
+func (c Crypt) messageAAD() []byte {
+	if !c.authenticated() {
+		return nil
+	}
+	return c.aad
+}
+
+// authenticated reports whether Encrypt produces an authenticated ciphertext.
+func (c Crypt) authenticated() bool {
 	switch c.method {
 	case METHOD_AES:
-		return aesEncrypt(src, c.key, c.iv, c.block, c.mode, c.padding)
-	case METHOD_DES, METHOD_DES3:
-		return desEncrypt(src, c.key, c.iv, c.block, c.mode, c.padding, c.method == METHOD_DES3)
+		return c.mode.aead() || c.mode == MODE_SIV
+	case METHOD_CHACHA20POLY1305, METHOD_XCHACHA20POLY1305:
+		return true
+	}
+	return false
+}
+
+func (c Crypt) encrypt(src, aad []byte) ([]byte, error) {
+	if aad != nil && !c.authenticated() {
+		return nil, fmt.Errorf("crypt %s.Encrypt: associated data requires an AEAD mode", c.method)
+	}
+	if c.mac != MAC_NONE {
+		return c.encryptThenMAC(src, nil)
+	}
+	switch c.method {
 	case METHOD_CHACHA20:
-		return chacha20Encrypt(src, c.key, c.iv)
-	case METHOD_BLOWFISH:
-		return blowfishEncrypt(src, c.block)
+		return chacha20Encrypt(src, c.key, c.password, c.iv, c.kdf, c.counter)
+	case METHOD_SALSA20, METHOD_XSALSA20:
+		return salsa20Encrypt(c.method, src, c.key, c.password, c.iv, c.kdf)
+	case METHOD_CHACHA20POLY1305, METHOD_XCHACHA20POLY1305:
+		return chacha20Poly1305Encrypt(c.method, src, c.key, c.password, c.iv, aad, c.kdf)
 	case METHOD_RC4:
 		return rc4Encrypt(src, c.key)
 	}
+	if blockCipherOf(c.method) != nil {
+		return c.blockEncrypt(src, aad)
+	}
 	return nil, fmt.Errorf("crypt.Encrypt unknown cipher method %d", c.method)
 }
 
-func (c Crypt) Decrypt(src []byte) ([]byte, error) {
+func (c Crypt) decrypt(src, aad []byte) ([]byte, error) {
+	if aad != nil && !c.authenticated() {
+		return nil, fmt.Errorf("crypt %s.Decrypt: associated data requires an AEAD mode", c.method)
+	}
+	if c.mac != MAC_NONE {
+		return c.verifyThenDecrypt(src, nil)
+	}
 	switch c.method {
-	case METHOD_AES:
-		return aesDecrypt(src, c.key, c.iv, c.block, c.mode, c.padding)
-	case METHOD_DES, METHOD_DES3:
-		return desDecrypt(src, c.key, c.iv, c.block, c.mode, c.padding, c.method == METHOD_DES3)
 	case METHOD_CHACHA20:
-		return chacha20Decrypt(src, c.key, c.iv)
-	case METHOD_BLOWFISH:
-		return blowfishDecrypt(src, c.block)
+		return chacha20Decrypt(src, c.key, c.password, c.iv, c.counter)
+	case METHOD_SALSA20, METHOD_XSALSA20:
+		return salsa20Decrypt(c.method, src, c.key, c.password, c.iv)
+	case METHOD_CHACHA20POLY1305, METHOD_XCHACHA20POLY1305:
+		return chacha20Poly1305Decrypt(c.method, src, c.key, c.password, c.iv, aad)
 	case METHOD_RC4:
 		return rc4Decrypt(src, c.key)
 	}
+	if blockCipherOf(c.method) != nil {
+		return c.blockDecrypt(src, aad)
+	}
 	return nil, fmt.Errorf("crypt.Decrypt unknown cipher method %d", c.method)
 }
 
-func verifyKey(method CipherMethod, key []byte) ([]byte, error) {
+// salted reports whether Encrypt derives the key and IV from a salted header.
+func (c Crypt) salted() bool {
+	switch c.method {
+	case METHOD_CHACHA20, METHOD_CHACHA20POLY1305, METHOD_XCHACHA20POLY1305, METHOD_SALSA20, METHOD_XSALSA20:
+		return c.iv == nil
+	case METHOD_RC4:
+		return false
+	}
+	return blockCipherOf(c.method) != nil && c.mode.Not(MODE_ECB, MODE_SIV, MODE_XTS) && c.iv == nil
+}
+
+// saltKeyByteSize returns the size of the key derived from a salted header,
+// or 0 if the method does not support it.
+func (c Crypt) saltKeyByteSize() int {
+	switch c.method {
+	case METHOD_CHACHA20, METHOD_CHACHA20POLY1305, METHOD_XCHACHA20POLY1305:
+		return chacha20SaltKeyByteSize
+	case METHOD_SALSA20, METHOD_XSALSA20:
+		return salsa20KeyByteSize
+	case METHOD_RC4:
+		return 0
+	}
+	if b := blockCipherOf(c.method); b != nil && !c.legacy {
+		return b.saltKeySize
+	}
+	return 0
+}
+
+// ivSize returns the size of the IV or nonce derived along with the key.
+func (c Crypt) ivSize() int {
+	switch c.method {
+	case METHOD_CHACHA20:
+		return chacha20SaltNonceByteSize
+	case METHOD_CHACHA20POLY1305, METHOD_XCHACHA20POLY1305:
+		return chacha20Poly1305NonceSize(c.method)
+	case METHOD_SALSA20, METHOD_XSALSA20:
+		return salsa20NonceSize(c.method)
+	case METHOD_RC4:
+		return 0
+	}
+	if c.mode.aead() {
+		return c.nonceSize
+	} else if c.mode == MODE_SIV || c.block == nil {
+		return 0
+	}
+	return derivedIVSize(c.block.BlockSize(), c.mode)
+}
+
+func newBlockCipher(method CipherMethod, key []byte) (cipher.Block, error) {
+	if b := blockCipherOf(method); b != nil {
+		return b.newCipher(key)
+	}
+	return nil, fmt.Errorf("crypt %s: not a block cipher", method)
+}
+
+func verifyKey(method CipherMethod, mode BlockMode, key []byte) ([]byte, error) {
 	var limit = map[CipherMethod][]int{
-		METHOD_AES:      {32, 24, 16},
-		METHOD_DES:      {8},
-		METHOD_DES3:     {24},
-		METHOD_CHACHA20: {32},
-		METHOD_BLOWFISH: {56},
-		METHOD_RC4:      {256},
+		METHOD_CHACHA20:          {32},
+		METHOD_RC4:               {256},
+		METHOD_CHACHA20POLY1305:  {32},
+		METHOD_XCHACHA20POLY1305: {32},
+		METHOD_SALSA20:           {32},
+		METHOD_XSALSA20:          {32},
+	}
+	if b := blockCipherOf(method); b != nil {
+		limit[method] = b.keySizes
+	}
+	// a key split in two halves is never cut down, that would move the split
+	var exact bool
+	if method == METHOD_AES && mode == MODE_SIV {
+		// two AES keys, for CMAC and CTR
+		limit[method] = []int{64, 48, 32}
+		exact = true
+	} else if method == METHOD_AES && mode == MODE_XTS {
+		// two AES-256 or AES-128 keys, for the data and the tweak
+		limit[method] = []int{64, 32}
+		exact = true
 	}
 	var length = len(key)
 	for _, n := range limit[method] {
-		if n == length {
+		if n == length || exact {
 			break
 		} else if length > n {
 			key = key[:n]
@@ -203,56 +539,41 @@ func verifyKey(method CipherMethod, key []byte) ([]byte, error) {
 	switch method {
 	case METHOD_BLOWFISH, METHOD_RC4:
 		if length < 1 || length > limit[method][0] {
-			return nil, fmt.Errorf("crypt %s: invalid key size %d", method, length)
+			return nil, &KeySizeError{Method: method, Got: length, Allowed: []int{1, limit[method][0]}}
 		}
 	default:
 		if !inSliceInt(length, limit[method]) {
-			return nil, fmt.Errorf("crypt %s: invalid key size %d", method, length)
+			var allowed = append([]int{}, limit[method]...)
+			sort.Ints(allowed)
+			return nil, &KeySizeError{Method: method, Got: length, Allowed: allowed}
 		}
 	}
 	return key, nil
 }
 
-func genSaltHeader(password []byte, blockSize int, mode BlockMode, keySize int) (header [16]byte, key, iv []byte) {
+func genSaltHeader(password []byte, ivSize, keySize int) (header [16]byte, key, iv []byte) {
 	var salt = genSalt()
-	var size = keySize
 	// 8 Bytes: Salted__
 	copy(header[:], append([]byte(saltedText), salt[:]...))
-	if mode.Has(MODE_GCM) {
-		size += gcmStandardNonceSize
-	} else if mode.Not(MODE_ECB) {
-		size += blockSize
-	}
-	key, iv = bytesToKey(salt, password, keySize, size)
+	key, iv = bytesToKey(salt, password, keySize, keySize+ivSize)
 	return
 }
 
-func parseSaltHeader(salt [saltTextByteSize]byte, password []byte, blockSize int, mode BlockMode, keySize int) (key, iv []byte) {
-	var size = keySize
-	if mode.Has(MODE_GCM) {
-		size += gcmStandardNonceSize
-	} else if mode.Not(MODE_ECB) {
-		size += blockSize
-	}
-	key, iv = bytesToKey(salt, password, keySize, size)
+func parseSaltHeader(salt [saltTextByteSize]byte, password []byte, ivSize, keySize int) (key, iv []byte) {
+	key, iv = bytesToKey(salt, password, keySize, keySize+ivSize)
 	return
 }
 
 func bytesToKey(salt [saltTextByteSize]byte, password []byte, keySize, minimum int) (key, iv []byte) {
-	a := append(password, salt[:]...)
-	b := MD5.Sum(a)
-	c := append([]byte{}, b...)
-	for len(c) < minimum {
-		b = MD5.Sum(append(b, a...))
-		c = append(c, b...)
-	}
+	c := evpBytesToKey(crypto.MD5, password, salt[:], minimum)
 	key = c[:keySize]
 	iv = c[keySize:minimum]
 	return
 }
 
 func getSalt(src []byte) (salt [saltTextByteSize]byte, ok bool) {
-	if len(src) >= 16 && bytes.Equal([]byte(saltedText), src[:8]) {
+	// OpenSSL writes Salted__
+	if len(src) >= 16 && (bytes.Equal([]byte(saltedText), src[:8]) || bytes.Equal([]byte(opensslSaltedText), src[:8])) {
 		copy(salt[:], src[8:16])
 		ok = true
 	}
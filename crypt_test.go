@@ -3,6 +3,8 @@ package crypt
 import (
 	"bytes"
 	"encoding/base64"
+	"encoding/hex"
+	"errors"
 	"testing"
 )
 
@@ -159,7 +161,7 @@ func TestBlowfish(t *testing.T) {
 	var ciphertext, plaintext []byte
 	var text = []byte("hello blowfish")
 
-	if c, err = NewBlowfish(key); err != nil {
+	if c, err = NewBlowfish(key, nil); err != nil {
 		t.Fatal(err)
 	}
 	if ciphertext, err = c.Encrypt(text); err != nil {
@@ -210,3 +212,81 @@ func TestMD5(t *testing.T) {
 func TestSha3(t *testing.T) {
 	t.Logf("SHA3 Shake128: %v\n", SHA3.Shake128([]byte("123"), 32))
 }
+
+func TestAAD(t *testing.T) {
+	var text = []byte("hello aad")
+	var aad = []byte("record-42")
+	c, err := NewAES(randBytes(32), nil, Options{Mode: MODE_GCM, AAD: aad})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ciphertext, err := c.Encrypt(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plaintext, err := c.DecryptWithAAD(ciphertext, aad)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(plaintext, text) {
+		t.Fatalf("AAD wrong")
+	}
+	if _, err = c.DecryptWithAAD(ciphertext, []byte("record-43")); !errors.Is(err, ErrAuthentication) {
+		t.Fatalf("AAD mismatch not detected: %v", err)
+	}
+	if _, err = NewAES(randBytes(32), nil, Options{Mode: MODE_CBC, AAD: aad}); err == nil {
+		t.Fatalf("AAD accepted without AEAD mode")
+	}
+
+	c, err = NewChaCha20(randBytes(32), nil, Options{AAD: aad})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ciphertext = sealSegments(t, c, text)
+	c.aad = []byte("record-43")
+	if _, err = openSegments(c, ciphertext); !errors.Is(err, ErrAuthentication) {
+		t.Fatalf("segmented stream AAD mismatch not detected: %v", err)
+	}
+}
+
+func TestChaCha20Poly1305(t *testing.T) {
+	// RFC 8439 section 2.8.2
+	key, _ := hex.DecodeString("808182838485868788898a8b8c8d8e8f909192939495969798999a9b9c9d9e9f")
+	nonce, _ := hex.DecodeString("070000004041424344454647")
+	aad, _ := hex.DecodeString("50515253c0c1c2c3c4c5c6c7")
+	text := []byte("Ladies and Gentlemen of the class of '99: If I could offer you only one tip for the future, sunscreen would be it.")
+	want := "d31a8d34648e60db7b86afbc53ef7ec2a4aded51296e08fea9e2b5a736ee62d63dbea45e8ca9671282fafb69da92728b1a71de0a9e060b2905d6a5b67ecd3b3692ddbd7f2d778b8c9803aee328091b58fab324e4fad675945585808b4831d7bc3ff4def08e4b7a9de576d26586cec64b6116" +
+		"1ae10b594f09e26a7e902ecbd0600691"
+	ciphertext, err := ChaCha20Poly1305.Encrypt(text, key, nonce, Options{AAD: aad})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hex.EncodeToString(ciphertext) != want {
+		t.Fatalf("ChaCha20-Poly1305 wrong: %x", ciphertext)
+	}
+
+	for _, method := range []CipherMethod{METHOD_CHACHA20POLY1305, METHOD_XCHACHA20POLY1305} {
+		c, err := newCrypt(method, key, nil, Options{AAD: aad})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ciphertext, err = c.Encrypt(text); err != nil {
+			t.Fatal(err)
+		}
+		plaintext, err := c.Decrypt(ciphertext)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(plaintext, text) {
+			t.Fatalf("%s wrong", method)
+		}
+		ciphertext[len(ciphertext)-1] ^= 1
+		if _, err = c.Decrypt(ciphertext); !errors.Is(err, ErrAuthentication) {
+			t.Fatalf("%s: modified ciphertext not detected: %v", method, err)
+		}
+	}
+
+	if _, err = NewXChaCha20Poly1305(key, nonce); err == nil {
+		t.Fatalf("XChaCha20-Poly1305 accepted a 12 byte nonce")
+	}
+}
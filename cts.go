@@ -0,0 +1,55 @@
+package crypt
+
+import (
+	"crypto/cipher"
+	"fmt"
+
+	ciphers "github.com/kayon/crypt/cipher"
+)
+
+// CTSVariant selects how MODE_CBC_CTS orders the last two ciphertext blocks,
+// see the NIST SP 800-38A Addendum.
+type CTSVariant uint8
+
+// The variants are numbered like ciphers.CS1 to ciphers.CS3.
+const (
+	// CTS_DEFAULT is the zero value, CTS_CS3.
+	CTS_DEFAULT CTSVariant = iota
+	// CTS_CS1 keeps the partial block before the last full one.
+	CTS_CS1
+	// CTS_CS2 is CBC for full blocks and CTS_CS3 otherwise.
+	CTS_CS2
+	// CTS_CS3 always swaps the last two blocks, as Kerberos does.
+	CTS_CS3
+)
+
+func (variant CTSVariant) String() string {
+	switch variant {
+	case CTS_CS1:
+		return "CS1"
+	case CTS_CS2:
+		return "CS2"
+	case CTS_DEFAULT, CTS_CS3:
+		return "CS3"
+	}
+	return ""
+}
+
+func (variant CTSVariant) cipher() int {
+	if variant == CTS_DEFAULT {
+		return ciphers.CS3
+	}
+	return int(variant)
+}
+
+// newCTS returns the MODE_CBC_CTS encrypter or decrypter. src must be at
+// least one block, ciphertext stealing needs a full block to steal from.
+func newCTS(method CipherMethod, block cipher.Block, iv []byte, variant CTSVariant, src []byte, encrypt bool) (cipher.BlockMode, error) {
+	if len(src) < block.BlockSize() {
+		return nil, fmt.Errorf("crypt %s: CBC-CTS input shorter than %d bytes", method, block.BlockSize())
+	}
+	if encrypt {
+		return ciphers.NewCBCCTSEncrypter(block, iv, variant.cipher()), nil
+	}
+	return ciphers.NewCBCCTSDecrypter(block, iv, variant.cipher()), nil
+}
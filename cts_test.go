@@ -0,0 +1,113 @@
+package crypt
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/hex"
+	"testing"
+
+	"golang.org/x/crypto/blowfish"
+
+	ciphers "github.com/kayon/crypt/cipher"
+)
+
+func TestCBCCTS(t *testing.T) {
+	// RFC 3962 appendix B, CS3 with a zero IV
+	var key = []byte("chicken teriyaki")
+	var iv = make([]byte, 16)
+	var text = []byte("I would like the General Gau's Chicken, please, and wonton soup.")
+	var vectors = []struct {
+		size       int
+		ciphertext string
+	}{
+		{17, "c6353568f2bf8cb4d8a580362da7ff7f97"},
+		{31, "fc00783e0efdb2c1d445d4c8eff7ed2297687268d6ecccc0c07b25e25ecfe5"},
+		{32, "39312523a78662d5be7fcbcc98ebf5a897687268d6ecccc0c07b25e25ecfe584"},
+		{47, "97687268d6ecccc0c07b25e25ecfe584b3fffd940c16a18c1b5549d2f838029e39312523a78662d5be7fcbcc98ebf5"},
+		{48, "97687268d6ecccc0c07b25e25ecfe5849dad8bbb96c4cdc03bc103e1a194bbd839312523a78662d5be7fcbcc98ebf5a8"},
+		{64, "97687268d6ecccc0c07b25e25ecfe58439312523a78662d5be7fcbcc98ebf5a84807efe836ee89a526730dbc2f7bc8409dad8bbb96c4cdc03bc103e1a194bbd8"},
+	}
+	for _, v := range vectors {
+		ciphertext, err := AES.Encrypt(text[:v.size], key, iv, Options{Mode: MODE_CBC_CTS})
+		if err != nil {
+			t.Fatal(v.size, err)
+		}
+		if hex.EncodeToString(ciphertext) != v.ciphertext {
+			t.Fatalf("%d: %x", v.size, ciphertext)
+		}
+		plaintext, err := AES.Decrypt(ciphertext, key, iv, Options{Mode: MODE_CBC_CTS})
+		if err != nil || !bytes.Equal(plaintext, text[:v.size]) {
+			t.Fatalf("%d: decrypt %q, %v", v.size, plaintext, err)
+		}
+	}
+
+	if CTS_CS1 != ciphers.CS1 || CTS_CS2 != ciphers.CS2 || CTS_CS3 != ciphers.CS3 {
+		t.Fatal("CTSVariant is not numbered like the cipher package")
+	}
+
+	// CS1 and CS2 only reorder the last two blocks of CS3
+	block, _ := aes.NewCipher(key)
+	for _, size := range []int{16, 17, 31, 32, 47, 64} {
+		cs3, _ := AES.Encrypt(text[:size], key, iv, Options{Mode: MODE_CBC_CTS})
+		if explicit, _ := AES.Encrypt(text[:size], key, iv, Options{Mode: MODE_CBC_CTS, CTS: CTS_CS3}); !bytes.Equal(explicit, cs3) {
+			t.Fatalf("%d: CTS_CS3 is not the default", size)
+		}
+		cs1, _ := AES.Encrypt(text[:size], key, iv, Options{Mode: MODE_CBC_CTS, CTS: CTS_CS1})
+		cs2, _ := AES.Encrypt(text[:size], key, iv, Options{Mode: MODE_CBC_CTS, CTS: CTS_CS2})
+		d := size % 16
+		if d == 0 {
+			d = 16
+		}
+		if size > 16 {
+			head := size - d - 16
+			want := append(append(append([]byte{}, cs3[:head]...), cs3[head+16:]...), cs3[head:head+16]...)
+			if !bytes.Equal(cs1, want) {
+				t.Fatalf("%d: CS1 %x", size, cs1)
+			}
+		}
+		if size%16 == 0 {
+			cbc := make([]byte, size)
+			cipher.NewCBCEncrypter(block, iv).CryptBlocks(cbc, text[:size])
+			if !bytes.Equal(cs2, cbc) {
+				t.Fatalf("%d: CS2 is not CBC", size)
+			}
+		} else if !bytes.Equal(cs2, cs3) {
+			t.Fatalf("%d: CS2 is not CS3", size)
+		}
+		for _, variant := range []CTSVariant{CTS_CS1, CTS_CS2, CTS_CS3} {
+			ciphertext, _ := AES.Encrypt(text[:size], key, iv, Options{Mode: MODE_CBC_CTS, CTS: variant})
+			plaintext, err := AES.Decrypt(ciphertext, key, iv, Options{Mode: MODE_CBC_CTS, CTS: variant})
+			if err != nil || !bytes.Equal(plaintext, text[:size]) {
+				t.Fatalf("%d %s: decrypt %q, %v", size, variant, plaintext, err)
+			}
+		}
+	}
+
+	// DES3 with a password, Blowfish through the cipher subpackage
+	c, err := NewDES3(testPassword, nil, Options{Mode: MODE_CBC_CTS, CTS: CTS_CS1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ciphertext, err := c.Encrypt(text[:21])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plaintext, err := c.Decrypt(ciphertext); err != nil || !bytes.Equal(plaintext, text[:21]) {
+		t.Fatalf("DES3: %q, %v", plaintext, err)
+	}
+	bf, _ := blowfish.NewCipher([]byte("blowfish key"))
+	buf := make([]byte, 21)
+	ciphers.NewCBCCTSEncrypter(bf, iv[:8], ciphers.CS2).CryptBlocks(buf, text[:21])
+	ciphers.NewCBCCTSDecrypter(bf, iv[:8], ciphers.CS2).CryptBlocks(buf, buf)
+	if !bytes.Equal(buf, text[:21]) {
+		t.Fatalf("Blowfish: %q", buf)
+	}
+
+	if _, err = AES.Encrypt(text[:15], key, iv, Options{Mode: MODE_CBC_CTS}); err == nil {
+		t.Fatal("encrypted less than a block")
+	}
+	if _, err = AES.Encrypt(text, key, iv, Options{Mode: MODE_CBC_CTS, CTS: 4}); err == nil {
+		t.Fatal("accepted an unknown variant")
+	}
+}
@@ -0,0 +1,286 @@
+package crypt
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"encoding/binary"
+	"fmt"
+)
+
+// Self-describing envelope written by Crypt.EncryptEnvelope and read by Open.
+//
+//	0  4  "CENV"
+//	4  1  envelope version
+//	5  1  CipherMethod
+//	6  1  BlockMode
+//	7  1  PaddingScheme
+//	8  1  KDF ID, 0 if the key is used as is
+//	9  1  salt length
+//	10 2  KDF parameters length, big endian
+//	12 1  nonce length
+//	13 1  tag length
+//	14 1  MACAlgorithm
+//	15 n  KDF parameters, see KDF.MarshalBinary
+//	.. m  salt
+//	.. k  nonce
+//	.. t  tag
+//	.. .. ciphertext
+//
+// Everything before the tag is authenticated as associated data by the AEAD
+// methods, or together with the ciphertext by the MAC.
+const (
+	envelopeMagic   = "CENV"
+	envelopeVersion = 2
+	envelopeSize    = 15
+)
+
+// EncryptEnvelope encrypts src into an envelope that records the method,
+// options, KDF parameters, nonce and tag, so Open can decrypt it with only the
+// password. The method must be an AEAD, or Options.MAC must be set so the
+// header and ciphertext are authenticated.
+//
+// If the Crypt was created with a password (no IV) the key is derived with
+// Options.KDF, PBKDF2 with the default parameters if it is nil. Otherwise the
+// key is used as is and must be given to Open. A random nonce is generated for
+// every envelope, the IV of the Crypt is not used.
+func (c Crypt) EncryptEnvelope(src []byte) ([]byte, error) {
+	return c.encryptEnvelope(src, c.messageAAD())
+}
+
+// EncryptEnvelopeWithAAD is like EncryptEnvelope but authenticates aad instead
+// of Options.AAD. The same aad must be given to OpenWithAAD.
+func (c Crypt) EncryptEnvelopeWithAAD(src, aad []byte) ([]byte, error) {
+	return c.encryptEnvelope(src, aad)
+}
+
+func (c Crypt) encryptEnvelope(src, aad []byte) ([]byte, error) {
+	if aad != nil && !c.authenticated() {
+		return nil, fmt.Errorf("crypt %s.EncryptEnvelope: associated data requires an AEAD mode", c.method)
+	}
+	if !c.salted() {
+		return c.sealEnvelope(src, aad, c.key, nil, nil)
+	}
+	var kdf = c.kdf
+	if kdf == nil {
+		kdf = PBKDF2{}
+	}
+	var salt = randBytes(kdfSaltByteSize)
+	key, err := kdf.DeriveKey(c.password, salt, c.saltKeyByteSize())
+	if err != nil {
+		return nil, err
+	}
+	return c.sealEnvelope(src, aad, key, kdf, salt)
+}
+
+// sealEnvelope writes the envelope of src encrypted with key, which was derived
+// by kdf from salt if kdf is not nil.
+func (c Crypt) sealEnvelope(src, aad, key []byte, kdf KDF, salt []byte) ([]byte, error) {
+	if err := c.checkEnvelope(); err != nil {
+		return nil, err
+	}
+	var params []byte
+	var err error
+	if kdf != nil {
+		if params, err = kdf.MarshalBinary(); err != nil {
+			return nil, err
+		}
+		if len(params) > 0xffff {
+			return nil, fmt.Errorf("crypt %s: parameters too large", kdf.ID())
+		}
+	}
+	var nonce = randBytes(c.envelopeNonceSize())
+	var tagSize = c.envelopeTagSize()
+
+	header := make([]byte, envelopeSize, envelopeSize+len(params)+len(salt)+len(nonce))
+	copy(header, envelopeMagic)
+	header[4] = envelopeVersion
+	header[5] = byte(c.method)
+	header[6] = byte(c.mode)
+	header[7] = byte(c.padding)
+	if kdf != nil {
+		header[8] = byte(kdf.ID())
+	}
+	header[9] = byte(len(salt))
+	binary.BigEndian.PutUint16(header[10:], uint16(len(params)))
+	header[12] = byte(len(nonce))
+	header[13] = byte(tagSize)
+	if c.mac != MAC_NONE {
+		header[13] = byte(c.mac.Size())
+	}
+	header[14] = byte(c.mac)
+	header = append(append(append(header, params...), salt...), nonce...)
+
+	dc, err := envelopeCrypt(c.method, c.mode, c.padding, key, nonce, tagSize)
+	if err != nil {
+		return nil, err
+	}
+	var ciphertext []byte
+	if c.mac != MAC_NONE {
+		if ciphertext, err = dc.encrypt(src, nil); err != nil {
+			return nil, err
+		}
+		m, err := c.mac.new(key)
+		if err != nil {
+			return nil, err
+		}
+		m.Write(header)
+		m.Write(ciphertext)
+		return append(m.Sum(header), ciphertext...), nil
+	}
+	if ciphertext, err = dc.encrypt(src, append(append([]byte{}, header...), aad...)); err != nil {
+		return nil, err
+	}
+	// move the tag in front of the ciphertext
+	var tag = ciphertext[len(ciphertext)-tagSize:]
+	return append(append(header, tag...), ciphertext[:len(ciphertext)-tagSize]...), nil
+}
+
+// checkEnvelope reports whether c can write an authenticated envelope.
+func (c Crypt) checkEnvelope() error {
+	if c.counter != 0 {
+		return fmt.Errorf("crypt %s: Options.Counter is not supported by the envelope", c.method)
+	} else if !c.authenticated() && c.mac == MAC_NONE {
+		return fmt.Errorf("crypt %s: the envelope requires an AEAD mode or Options.MAC", c.method)
+	}
+	return nil
+}
+
+// Open decrypts an envelope written by Crypt.EncryptEnvelope. password is the
+// key the Crypt was created with.
+func Open(ciphertext, password []byte) ([]byte, error) {
+	return OpenWithAAD(ciphertext, password, nil)
+}
+
+// OpenWithAAD is like Open for envelopes with associated data. It returns an
+// error wrapping ErrAuthentication if aad does not match.
+func OpenWithAAD(ciphertext, password, aad []byte) ([]byte, error) {
+	e, err := parseEnvelope(ciphertext)
+	if err != nil {
+		return nil, err
+	}
+	return e.open(password, aad)
+}
+
+// envelope is a parsed envelope, see Crypt.EncryptEnvelope.
+type envelope struct {
+	method  CipherMethod
+	mode    BlockMode
+	padding PaddingScheme
+	mac     MACAlgorithm
+	kdfID   KDFID
+	header  []byte // everything before the tag
+	params  []byte
+	salt    []byte
+	nonce   []byte
+	tag     []byte
+	body    []byte
+}
+
+func parseEnvelope(ciphertext []byte) (*envelope, error) {
+	if len(ciphertext) < 5 || !bytes.Equal(ciphertext[:4], []byte(envelopeMagic)) {
+		return nil, fmt.Errorf("crypt Open: not an envelope")
+	} else if ciphertext[4] != envelopeVersion {
+		return nil, fmt.Errorf("crypt Open: unsupported envelope version %d", ciphertext[4])
+	} else if len(ciphertext) < envelopeSize {
+		return nil, fmt.Errorf("crypt Open: truncated envelope")
+	}
+	var e = &envelope{
+		method:  CipherMethod(ciphertext[5]),
+		mode:    BlockMode(ciphertext[6]),
+		padding: PaddingScheme(ciphertext[7]),
+		kdfID:   KDFID(ciphertext[8]),
+		mac:     MACAlgorithm(ciphertext[14]),
+	}
+	var paramsSize = int(binary.BigEndian.Uint16(ciphertext[10:]))
+	var saltSize, nonceSize, tagSize = int(ciphertext[9]), int(ciphertext[12]), int(ciphertext[13])
+	var offset = envelopeSize + paramsSize + saltSize + nonceSize
+	if len(ciphertext) < offset+tagSize {
+		return nil, fmt.Errorf("crypt Open: truncated envelope")
+	}
+	e.header = ciphertext[:offset]
+	e.params = e.header[envelopeSize : envelopeSize+paramsSize]
+	e.salt = e.header[envelopeSize+paramsSize : envelopeSize+paramsSize+saltSize]
+	e.nonce = e.header[offset-nonceSize:]
+	e.tag = ciphertext[offset : offset+tagSize]
+	e.body = ciphertext[offset+tagSize:]
+	return e, nil
+}
+
+func (e *envelope) open(password, aad []byte) ([]byte, error) {
+	var key = password
+	if e.kdfID != 0 {
+		kdf, err := unmarshalKDF(e.kdfID, e.params)
+		if err != nil {
+			return nil, err
+		}
+		if key, err = kdf.DeriveKey(password, e.salt, Crypt{method: e.method}.saltKeyByteSize()); err != nil {
+			return nil, err
+		}
+	}
+	var tagSize = len(e.tag)
+	if e.mac != MAC_NONE {
+		tagSize = 0
+	}
+	dc, err := envelopeCrypt(e.method, e.mode, e.padding, key, e.nonce, tagSize)
+	if err != nil {
+		return nil, err
+	}
+	if len(e.nonce) != dc.envelopeNonceSize() {
+		return nil, fmt.Errorf("crypt Open: invalid envelope")
+	}
+	if e.mac != MAC_NONE {
+		if dc.authenticated() || e.mac.Size() == 0 || len(e.tag) != e.mac.Size() {
+			return nil, fmt.Errorf("crypt Open: invalid envelope")
+		} else if aad != nil {
+			return nil, fmt.Errorf("crypt %s.Open: associated data requires an AEAD mode", e.method)
+		}
+		m, err := e.mac.new(key)
+		if err != nil {
+			return nil, err
+		}
+		m.Write(e.header)
+		m.Write(e.body)
+		if !hmac.Equal(m.Sum(nil), e.tag) {
+			return nil, fmt.Errorf("crypt %s.Open: %s %w", e.method, e.mac, ErrAuthentication)
+		}
+		return dc.decrypt(e.body, nil)
+	}
+	// EncryptEnvelope never writes an unauthenticated envelope
+	if !dc.authenticated() || len(e.tag) != dc.envelopeTagSize() {
+		return nil, fmt.Errorf("crypt Open: invalid envelope")
+	}
+	var src = append(append([]byte{}, e.body...), e.tag...)
+	return dc.decrypt(src, append(append([]byte{}, e.header...), aad...))
+}
+
+// envelopeCrypt returns the Crypt that encrypts the envelope body with key
+// and nonce. tagSize is only used by the AES AEAD modes.
+func envelopeCrypt(method CipherMethod, mode BlockMode, padding PaddingScheme, key, nonce []byte, tagSize int) (*Crypt, error) {
+	if len(nonce) == 0 {
+		nonce = nil
+	}
+	if method == METHOD_RC4 {
+		return NewRC4(key)
+	}
+	var opts = Options{Mode: mode, Padding: padding}
+	if method == METHOD_AES && mode.aead() {
+		opts.TagSize = tagSize
+	}
+	return newCrypt(method, key, nonce, opts)
+}
+
+func (c Crypt) envelopeNonceSize() int {
+	if c.method == METHOD_RC4 {
+		return 0
+	}
+	return c.ivSize()
+}
+
+func (c Crypt) envelopeTagSize() int {
+	if !c.authenticated() {
+		return 0
+	} else if c.method == METHOD_AES {
+		return c.tagSize
+	}
+	return 16
+}
@@ -0,0 +1,243 @@
+package crypt
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestEnvelope(t *testing.T) {
+	var text = []byte("Pack my box with five dozen liquor jugs")
+	var fastKDF = Scrypt{N: 1 << 10}
+	var crypts = map[string]func() (*Crypt, error){
+		"AES/CBC": func() (*Crypt, error) {
+			return NewAES([]byte("password"), nil, Options{KDF: fastKDF, MAC: MAC_HMAC_SHA256})
+		},
+		"AES/CTR": func() (*Crypt, error) {
+			return NewAES([]byte("password"), nil, Options{Mode: MODE_CTR, KDF: fastKDF, MAC: MAC_CMAC})
+		},
+		"AES/GCM": func() (*Crypt, error) { return NewAES([]byte("password"), nil, Options{Mode: MODE_GCM, KDF: fastKDF}) },
+		"AES/ECB": func() (*Crypt, error) {
+			return NewAES([]byte("15234c27ef5da06b"), nil, Options{Mode: MODE_ECB, Padding: PAD_ANSIX923, MAC: MAC_HMAC_SHA256})
+		},
+		"AES/key": func() (*Crypt, error) {
+			return NewAES([]byte("15234c27ef5da06b"), randBytes(16), Options{Mode: MODE_OFB, MAC: MAC_HMAC_SHA512})
+		},
+		"DES3/CFB": func() (*Crypt, error) {
+			return NewDES3([]byte("password"), nil, Options{Mode: MODE_CFB, KDF: fastKDF, MAC: MAC_HMAC_SHA256})
+		},
+		"ChaCha20": func() (*Crypt, error) {
+			return NewChaCha20([]byte("password"), nil, Options{KDF: fastKDF, MAC: MAC_HMAC_SHA256})
+		},
+		"XSalsa20": func() (*Crypt, error) {
+			return NewXSalsa20([]byte("password"), nil, Options{KDF: fastKDF, MAC: MAC_HMAC_SHA256})
+		},
+		"XChaCha20": func() (*Crypt, error) { return NewXChaCha20Poly1305([]byte("password"), nil, Options{KDF: fastKDF}) },
+		"Blowfish":  func() (*Crypt, error) { return NewBlowfish([]byte("password"), nil, Options{MAC: MAC_HMAC_SHA256}) },
+	}
+	for name, fn := range crypts {
+		c, err := fn()
+		if err != nil {
+			t.Fatal(name, err)
+		}
+		ciphertext, err := c.EncryptEnvelope(text)
+		if err != nil {
+			t.Fatal(name, err)
+		}
+		password := []byte("password")
+		if name == "AES/ECB" || name == "AES/key" {
+			password = []byte("15234c27ef5da06b")
+		}
+		plaintext, err := Open(ciphertext, password)
+		if err != nil {
+			t.Fatal(name, err)
+		}
+		if !bytes.Equal(plaintext, text) {
+			t.Fatalf("%s: wrong plaintext %q", name, plaintext)
+		}
+	}
+
+	// the unauthenticated methods require Options.MAC
+	c, err := NewAES([]byte("password"), nil, Options{Mode: MODE_CTR, KDF: fastKDF})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = c.EncryptEnvelope(text); err == nil {
+		t.Fatal("expected an error for an unauthenticated envelope")
+	}
+	rc4, _ := NewRC4([]byte("password"))
+	if _, err = rc4.EncryptEnvelope(text); err == nil {
+		t.Fatal("expected an error for an RC4 envelope")
+	}
+
+	// the header and ciphertext are authenticated by the MAC
+	if c, err = NewAES([]byte("password"), nil, Options{Mode: MODE_CTR, KDF: fastKDF, MAC: MAC_HMAC_SHA256}); err != nil {
+		t.Fatal(err)
+	}
+	ciphertext, err := c.EncryptEnvelope(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, i := range []int{6, 14, len(ciphertext) - 1} {
+		modified := append([]byte{}, ciphertext...)
+		modified[i] ^= 1
+		if _, err = Open(modified, []byte("password")); err == nil {
+			t.Fatalf("expected an error for a modified byte %d", i)
+		}
+	}
+	if _, err = OpenWithAAD(ciphertext, []byte("password"), []byte("aad")); err == nil {
+		t.Fatal("expected an error for associated data with a MAC")
+	}
+	// dropping the MAC must not give an unauthenticated envelope, neither as
+	// the old version 1 without the MAC byte nor with MAC_NONE
+	e, err := parseEnvelope(ciphertext)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var body = append([]byte{}, e.body...)
+	body[0] ^= 1
+	var v1 = append([]byte{}, ciphertext[:envelopeSize-1]...)
+	v1[4], v1[13] = 1, 0
+	var stripped = append([]byte{}, e.header...)
+	stripped[13], stripped[14] = 0, 0
+	for name, modified := range map[string][]byte{
+		"version 1": append(append(v1, e.header[envelopeSize:]...), body...),
+		"MAC_NONE":  append(stripped, body...),
+	} {
+		if plaintext, err := Open(modified, []byte("password")); err == nil {
+			t.Fatalf("%s: opened without the MAC: %q", name, plaintext)
+		}
+	}
+
+	// the header is authenticated by AEAD methods
+	if c, err = NewAES([]byte("password"), nil, Options{Mode: MODE_GCM, KDF: fastKDF}); err != nil {
+		t.Fatal(err)
+	}
+	ciphertext, err = c.EncryptEnvelopeWithAAD(text, []byte("aad"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = OpenWithAAD(ciphertext, []byte("password"), []byte("aad")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = Open(ciphertext, []byte("password")); !errors.Is(err, ErrAuthentication) {
+		t.Fatal("expected authentication error without aad", err)
+	}
+	ciphertext[7] ^= 1
+	if _, err = OpenWithAAD(ciphertext, []byte("password"), []byte("aad")); !errors.Is(err, ErrAuthentication) {
+		t.Fatal("expected authentication error for a modified header", err)
+	}
+	if _, err = Open(ciphertext[:envelopeSize-1], []byte("password")); err == nil {
+		t.Fatal("expected error for a truncated envelope")
+	}
+	if _, err = Open(text, []byte("password")); err == nil {
+		t.Fatal("expected error for a missing envelope")
+	}
+}
+
+func TestEnvelopeCrypt(t *testing.T) {
+	var text = []byte("Pack my box with five dozen liquor jugs")
+	oldMaster, err := NewAESKeyWrap(randBytes(32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	newMaster, err := NewAESGCMKeyWrap(randBytes(16))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var methods = []struct {
+		method CipherMethod
+		opts   Options
+	}{
+		{METHOD_AES, Options{Mode: MODE_GCM, AAD: []byte("aad")}},
+		{METHOD_AES, Options{Mode: MODE_CBC, MAC: MAC_HMAC_SHA256}},
+		{METHOD_DES3, Options{Mode: MODE_CTR, MAC: MAC_CMAC}},
+		{METHOD_XCHACHA20POLY1305, Options{}},
+		{METHOD_BLOWFISH, Options{MAC: MAC_HMAC_SHA256}},
+	}
+	for _, m := range methods {
+		e, err := NewEnvelopeCrypt(oldMaster, m.method, m.opts)
+		if err != nil {
+			t.Fatal(m.method, err)
+		}
+		ciphertext, err := e.Encrypt(text)
+		if err != nil {
+			t.Fatal(m.method, err)
+		}
+		plaintext, err := e.Decrypt(ciphertext)
+		if err != nil {
+			t.Fatal(m.method, err)
+		}
+		if !bytes.Equal(plaintext, text) {
+			t.Fatalf("%s: wrong plaintext %q", m.method, plaintext)
+		}
+
+		rewrapped, err := e.Rewrap(ciphertext, newMaster)
+		if err != nil {
+			t.Fatal(m.method, err)
+		}
+		rotated, err := NewEnvelopeCrypt(newMaster, m.method, m.opts)
+		if err != nil {
+			t.Fatal(m.method, err)
+		}
+		if plaintext, err = rotated.Decrypt(rewrapped); err != nil || !bytes.Equal(plaintext, text) {
+			t.Fatal(m.method, "rewrapped decrypt failed", err)
+		}
+		_, body, _ := parseDataKey(ciphertext)
+		if _, rewrappedBody, _ := parseDataKey(rewrapped); !bytes.Equal(rewrappedBody, body) {
+			t.Fatal(m.method, "rewrap changed the data")
+		}
+		if _, err = e.Decrypt(rewrapped); !errors.Is(err, ErrAuthentication) {
+			t.Fatal(m.method, "expected authentication error with the old master key", err)
+		}
+	}
+}
+
+func TestEnvelopeCryptRelabel(t *testing.T) {
+	var text = []byte("Pack my box with five dozen liquor jugs")
+	master, err := NewAESKeyWrap(randBytes(32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	gcm, err := NewEnvelopeCrypt(master, METHOD_AES, Options{Mode: MODE_GCM})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ciphertext, err := gcm.Encrypt(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a GCM envelope relabelled as a CTR envelope without a tag, the counter
+	// block of GCM is the nonce followed by 2
+	wrapped, body, _ := parseDataKey(ciphertext)
+	var nonce = body[envelopeSize : envelopeSize+12]
+	var header = append([]byte{}, body[:envelopeSize]...)
+	header[6] = byte(MODE_CTR)
+	header[12] = 16
+	header[13] = 0
+	header = append(append(header, nonce...), 0, 0, 0, 2)
+	var relabelled = append(header, body[envelopeSize+12+16:]...)
+	key, _ := master.UnwrapKey(wrapped)
+	if plaintext, err := Open(relabelled, key); err == nil {
+		t.Fatalf("opened a relabelled envelope: %q", plaintext)
+	}
+	relabelled = append(ciphertext[:len(ciphertext)-len(body):len(ciphertext)-len(body)], relabelled...)
+	if _, err = gcm.Decrypt(relabelled); err == nil {
+		t.Fatal("expected an error for a relabelled envelope")
+	}
+
+	ctr, err := NewEnvelopeCrypt(master, METHOD_AES, Options{Mode: MODE_CTR, MAC: MAC_HMAC_SHA256})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ciphertext, err = ctr.Encrypt(text); err != nil {
+		t.Fatal(err)
+	}
+	cbc, _ := NewEnvelopeCrypt(master, METHOD_AES, Options{Mode: MODE_CBC, MAC: MAC_HMAC_SHA256})
+	if _, err = cbc.Decrypt(ciphertext); err == nil {
+		t.Fatal("expected an error for another mode")
+	}
+	if _, err = NewEnvelopeCrypt(master, METHOD_AES, Options{Mode: MODE_CTR}); err == nil {
+		t.Fatal("expected an error without Options.MAC")
+	}
+}
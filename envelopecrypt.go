@@ -0,0 +1,130 @@
+package crypt
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+)
+
+// Data key envelope written by EnvelopeCrypt.
+//
+//	0  4  "CDEK"
+//	4  1  version
+//	5  2  wrapped key length, big endian
+//	7  n  wrapped key
+//	.. .. envelope, see Crypt.EncryptEnvelope
+const (
+	dataKeyMagic      = "CDEK"
+	dataKeyVersion    = 1
+	dataKeyHeaderSize = 7
+)
+
+// EnvelopeCrypt encrypts every message with a random data key and stores the
+// data key wrapped under a master key next to the ciphertext. Master keys are
+// rotated with Rewrap, which does not touch the encrypted data.
+type EnvelopeCrypt struct {
+	wrapper KeyWrapper
+	crypt   *Crypt
+}
+
+// NewEnvelopeCrypt returns an EnvelopeCrypt encrypting data with method and
+// Options, and wrapping data keys with wrapper. Options.KDF is not used. The
+// method must be an AEAD or Options.MAC must be set.
+func NewEnvelopeCrypt(wrapper KeyWrapper, method CipherMethod, args ...Options) (*EnvelopeCrypt, error) {
+	if wrapper == nil {
+		return nil, fmt.Errorf("crypt EnvelopeCrypt: nil KeyWrapper")
+	}
+	c, err := newCrypt(method, randBytes(dataKeySize(method)), nil, args...)
+	if err != nil {
+		return nil, err
+	} else if err = c.checkEnvelope(); err != nil {
+		return nil, err
+	}
+	return &EnvelopeCrypt{wrapper: wrapper, crypt: c}, nil
+}
+
+func (e EnvelopeCrypt) Encrypt(src []byte) ([]byte, error) {
+	var key = randBytes(dataKeySize(e.crypt.method))
+	wrapped, err := e.wrapper.WrapKey(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(wrapped) > 0xffff {
+		return nil, fmt.Errorf("crypt EnvelopeCrypt.Encrypt: wrapped key too large")
+	}
+	body, err := e.crypt.sealEnvelope(src, e.crypt.messageAAD(), key, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	var ciphertext = make([]byte, dataKeyHeaderSize, dataKeyHeaderSize+len(wrapped)+len(body))
+	copy(ciphertext, dataKeyMagic)
+	ciphertext[4] = dataKeyVersion
+	binary.BigEndian.PutUint16(ciphertext[5:], uint16(len(wrapped)))
+	return append(append(ciphertext, wrapped...), body...), nil
+}
+
+// Decrypt decrypts src, which must have been encrypted with the method, mode
+// and MAC of e. The envelope header is not trusted to pick them.
+func (e EnvelopeCrypt) Decrypt(src []byte) ([]byte, error) {
+	wrapped, body, err := parseDataKey(src)
+	if err != nil {
+		return nil, err
+	}
+	env, err := parseEnvelope(body)
+	if err != nil {
+		return nil, err
+	}
+	if env.method != e.crypt.method || env.mode != e.crypt.mode || env.mac != e.crypt.mac {
+		return nil, fmt.Errorf("crypt EnvelopeCrypt.Decrypt: envelope does not have the method, mode and MAC of the EnvelopeCrypt")
+	}
+	key, err := e.wrapper.UnwrapKey(wrapped)
+	if err != nil {
+		return nil, err
+	}
+	return env.open(key, e.crypt.messageAAD())
+}
+
+// Rewrap returns src with its data key wrapped by wrapper instead of the master
+// key of e. The encrypted data is copied unchanged.
+func (e EnvelopeCrypt) Rewrap(src []byte, wrapper KeyWrapper) ([]byte, error) {
+	wrapped, body, err := parseDataKey(src)
+	if err != nil {
+		return nil, err
+	}
+	key, err := e.wrapper.UnwrapKey(wrapped)
+	if err != nil {
+		return nil, err
+	}
+	if wrapped, err = wrapper.WrapKey(key); err != nil {
+		return nil, err
+	}
+	if len(wrapped) > 0xffff {
+		return nil, fmt.Errorf("crypt EnvelopeCrypt.Rewrap: wrapped key too large")
+	}
+	var ciphertext = make([]byte, dataKeyHeaderSize, dataKeyHeaderSize+len(wrapped)+len(body))
+	copy(ciphertext, src[:5])
+	binary.BigEndian.PutUint16(ciphertext[5:], uint16(len(wrapped)))
+	return append(append(ciphertext, wrapped...), body...), nil
+}
+
+func parseDataKey(src []byte) (wrapped, body []byte, err error) {
+	if len(src) < dataKeyHeaderSize || !bytes.Equal(src[:4], []byte(dataKeyMagic)) {
+		return nil, nil, fmt.Errorf("crypt EnvelopeCrypt: not a data key envelope")
+	}
+	if src[4] != dataKeyVersion {
+		return nil, nil, fmt.Errorf("crypt EnvelopeCrypt: unsupported version %d", src[4])
+	}
+	var n = dataKeyHeaderSize + int(binary.BigEndian.Uint16(src[5:]))
+	if len(src) < n {
+		return nil, nil, fmt.Errorf("crypt EnvelopeCrypt: truncated data key")
+	}
+	return src[dataKeyHeaderSize:n], src[n:], nil
+}
+
+// dataKeySize returns the size of the random data keys of method.
+func dataKeySize(method CipherMethod) int {
+	if size := (Crypt{method: method}).saltKeyByteSize(); size > 0 {
+		return size
+	}
+	return 32
+}
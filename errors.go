@@ -0,0 +1,65 @@
+package crypt
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrAuthentication is returned when a ciphertext or its associated data
+	// fails to authenticate.
+	ErrAuthentication = errors.New("crypt: message authentication failed")
+
+	// ErrInvalidPadding is returned when a decrypted ciphertext is not
+	// correctly padded or not a multiple of the block size. It carries no
+	// detail on purpose, so it cannot be used as a padding oracle.
+	ErrInvalidPadding = errors.New("crypt: invalid padding")
+
+	// ErrInvalidNonce is returned when a nonce or IV has the wrong size for
+	// the method and mode.
+	ErrInvalidNonce = errors.New("crypt: invalid nonce size")
+)
+
+// KeySizeError is returned for a key the method cannot use.
+type KeySizeError struct {
+	Method CipherMethod
+	// Name is set instead of Method for a MAC key, such as Poly1305.
+	Name string
+	Got  int
+	// Allowed are the valid key sizes. For Blowfish and RC4, which take keys
+	// of any length in a range, it is the minimum and maximum.
+	Allowed []int
+}
+
+func (e *KeySizeError) Error() string {
+	var sep = ", "
+	if e.Method == METHOD_BLOWFISH || e.Method == METHOD_RC4 {
+		sep = " to "
+	}
+	var name = e.Method.String()
+	if e.Name != "" {
+		name = e.Name
+	}
+	return fmt.Sprintf("crypt %s: invalid key size %d, must be %s", name, e.Got, joinInts(e.Allowed, sep))
+}
+
+// nonceSizeError returns an error wrapping ErrInvalidNonce.
+func nonceSizeError(method CipherMethod, got int, want ...int) error {
+	return fmt.Errorf("crypt %s: %w %d, must be %s", method, ErrInvalidNonce, got, joinInts(want, ", "))
+}
+
+// nonceRangeError is nonceSizeError for a method that takes any nonce size
+// from min to max.
+func nonceRangeError(method CipherMethod, got, min, max int) error {
+	return fmt.Errorf("crypt %s: %w %d, must be %d to %d", method, ErrInvalidNonce, got, min, max)
+}
+
+func joinInts(values []int, sep string) string {
+	var s = make([]string, len(values))
+	for i, n := range values {
+		s[i] = strconv.Itoa(n)
+	}
+	return strings.Join(s, sep)
+}
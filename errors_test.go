@@ -0,0 +1,65 @@
+package crypt
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestErrors(t *testing.T) {
+	var keySizeErr *KeySizeError
+	_, err := NewAES(make([]byte, 5), make([]byte, 16))
+	if !errors.As(err, &keySizeErr) || keySizeErr.Method != METHOD_AES || keySizeErr.Got != 5 || len(keySizeErr.Allowed) != 3 {
+		t.Fatalf("expected AES KeySizeError, got %v", err)
+	}
+	_, err = NewBlowfish(nil, nil)
+	if !errors.As(err, &keySizeErr) || keySizeErr.Method != METHOD_BLOWFISH || keySizeErr.Allowed[1] != 56 {
+		t.Fatalf("expected Blowfish KeySizeError, got %v", err)
+	}
+
+	for _, fn := range []func() (*Crypt, error){
+		func() (*Crypt, error) { return NewAES(make([]byte, 16), make([]byte, 8)) },
+		func() (*Crypt, error) { return NewAES(make([]byte, 16), make([]byte, 16), Options{Mode: MODE_GCM}) },
+		func() (*Crypt, error) { return NewChaCha20(make([]byte, 32), make([]byte, 16)) },
+		func() (*Crypt, error) { return NewXChaCha20Poly1305(make([]byte, 32), make([]byte, 12)) },
+		func() (*Crypt, error) { return NewSalsa20(make([]byte, 32), make([]byte, 24)) },
+	} {
+		if _, err = fn(); !errors.Is(err, ErrInvalidNonce) {
+			t.Fatalf("expected ErrInvalidNonce, got %v", err)
+		}
+	}
+
+	// every malformed padding gives the same error
+	var block = bytes.Repeat([]byte{7}, 16)
+	for _, last := range [][]byte{{0}, {17}, {2, 3, 2}, {3, 3, 3, 2}} {
+		padded := append(append([]byte{}, block[:16-len(last)]...), last...)
+		if _, err = PKCS7UnPadding(padded, 16); err != ErrInvalidPadding {
+			t.Fatalf("%v: expected ErrInvalidPadding, got %v", last, err)
+		}
+	}
+	for _, scheme := range []PaddingScheme{PAD_PKCS7, PAD_ISO97971, PAD_ANSIX923, PAD_ISO10126} {
+		if _, err = UnPadding(scheme, nil, 16); err != ErrInvalidPadding {
+			t.Fatalf("%s: expected ErrInvalidPadding, got %v", scheme, err)
+		}
+	}
+	c, err := NewAES(make([]byte, 16), make([]byte, 16))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = c.Decrypt(make([]byte, 15)); !errors.Is(err, ErrInvalidPadding) {
+		t.Fatalf("expected ErrInvalidPadding, got %v", err)
+	}
+
+	c, err = NewAES(make([]byte, 16), make([]byte, 12), Options{Mode: MODE_GCM})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ciphertext, err := c.Encrypt([]byte("text"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ciphertext[0] ^= 1
+	if _, err = c.Decrypt(ciphertext); !errors.Is(err, ErrAuthentication) {
+		t.Fatalf("expected ErrAuthentication, got %v", err)
+	}
+}
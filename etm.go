@@ -0,0 +1,158 @@
+package crypt
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	"fmt"
+	"hash"
+	"io"
+
+	"golang.org/x/crypto/hkdf"
+
+	ciphers "github.com/kayon/crypt/cipher"
+)
+
+// MACAlgorithm selects the encrypt-then-MAC tag of the unauthenticated modes,
+// see Options.MAC.
+type MACAlgorithm uint8
+
+const (
+	MAC_NONE MACAlgorithm = iota
+	MAC_HMAC_SHA256
+	MAC_HMAC_SHA512
+	// MAC_CMAC is AES-256-CMAC.
+	MAC_CMAC
+)
+
+func (mac MACAlgorithm) String() string {
+	switch mac {
+	case MAC_NONE:
+		return "None"
+	case MAC_HMAC_SHA256:
+		return "HMAC-SHA256"
+	case MAC_HMAC_SHA512:
+		return "HMAC-SHA512"
+	case MAC_CMAC:
+		return "CMAC"
+	}
+	return ""
+}
+
+// Size returns the size of the tag.
+func (mac MACAlgorithm) Size() int {
+	switch mac {
+	case MAC_HMAC_SHA256:
+		return sha256.Size
+	case MAC_HMAC_SHA512:
+		return sha512.Size
+	case MAC_CMAC:
+		return aes.BlockSize
+	}
+	return 0
+}
+
+func (mac MACAlgorithm) keySize() int {
+	if mac == MAC_HMAC_SHA512 {
+		return sha512.Size
+	}
+	return 32
+}
+
+// new returns the MAC keyed with a key derived from the cipher key, so the
+// cipher key itself is never used for both.
+func (mac MACAlgorithm) new(cipherKey []byte) (hash.Hash, error) {
+	var key = make([]byte, mac.keySize())
+	if _, err := io.ReadFull(hkdf.New(sha256.New, cipherKey, nil, []byte("crypt encrypt-then-MAC")), key); err != nil {
+		return nil, err
+	}
+	switch mac {
+	case MAC_HMAC_SHA256:
+		return hmac.New(sha256.New, key), nil
+	case MAC_HMAC_SHA512:
+		return hmac.New(sha512.New, key), nil
+	case MAC_CMAC:
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, err
+		}
+		return ciphers.NewCMAC(block)
+	}
+	return nil, fmt.Errorf("crypt: unknown MAC %d", mac)
+}
+
+// encryptThenMAC encrypts src and appends a tag over aad, the IV, the header
+// and the ciphertext. aad must carry its own length, like the Keyring header.
+func (c Crypt) encryptThenMAC(src, aad []byte) ([]byte, error) {
+	var header []byte
+	var key, iv, block = c.key, c.iv, c.block
+	var err error
+	if c.salted() {
+		if header, key, iv, err = genHeader(c.kdf, c.key, c.password, c.ivSize(), c.saltKeyByteSize()); err != nil {
+			return nil, err
+		}
+		if block != nil {
+			if block, err = newBlockCipher(c.method, key); err != nil {
+				return nil, err
+			}
+		}
+	}
+	body, err := c.withKey(key, iv, block).encrypt(src, nil)
+	if err != nil {
+		return nil, err
+	}
+	m, err := c.mac.new(key)
+	if err != nil {
+		return nil, err
+	}
+	var ciphertext = append(header, body...)
+	m.Write(aad)
+	m.Write(iv)
+	m.Write(ciphertext)
+	return m.Sum(ciphertext), nil
+}
+
+// verifyThenDecrypt checks the tag appended by encryptThenMAC in constant time
+// before anything is decrypted or unpadded.
+func (c Crypt) verifyThenDecrypt(src, aad []byte) ([]byte, error) {
+	var size = c.mac.Size()
+	if len(src) < size {
+		return nil, fmt.Errorf("crypt %s.Decrypt: %s %w", c.method, c.mac, ErrAuthentication)
+	}
+	var ciphertext, tag = src[:len(src)-size], src[len(src)-size:]
+	var key, iv, block = c.key, c.iv, c.block
+	var offset int
+	if c.saltKeyByteSize() > 0 {
+		var derivedKey, derivedIV []byte
+		var err error
+		if offset, derivedKey, derivedIV, err = parseHeader(ciphertext, c.key, c.password, c.ivSize(), c.saltKeyByteSize()); err != nil {
+			return nil, err
+		} else if offset > 0 {
+			key, iv = derivedKey, derivedIV
+			if block != nil {
+				if block, err = newBlockCipher(c.method, key); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+	m, err := c.mac.new(key)
+	if err != nil {
+		return nil, err
+	}
+	m.Write(aad)
+	m.Write(iv)
+	m.Write(ciphertext)
+	if !hmac.Equal(m.Sum(nil), tag) {
+		return nil, fmt.Errorf("crypt %s.Decrypt: %s %w", c.method, c.mac, ErrAuthentication)
+	}
+	return c.withKey(key, iv, block).decrypt(ciphertext[offset:], nil)
+}
+
+// withKey returns a copy of c without MAC that encrypts with key and iv.
+func (c Crypt) withKey(key, iv []byte, block cipher.Block) Crypt {
+	c.key, c.iv, c.block, c.mac = key, iv, block, MAC_NONE
+	return c
+}
@@ -0,0 +1,106 @@
+package crypt
+
+import (
+	"bytes"
+	"crypto/aes"
+	"encoding/hex"
+	"errors"
+	"io"
+	"testing"
+
+	ciphers "github.com/kayon/crypt/cipher"
+)
+
+func TestEncryptThenMAC(t *testing.T) {
+	var text = []byte("Pack my box with five dozen liquor jugs")
+	for mac := MAC_HMAC_SHA256; mac <= MAC_CMAC; mac++ {
+		var crypts = map[string]func() (*Crypt, error){
+			"AES/CBC":    func() (*Crypt, error) { return NewAES(testPassword, nil, Options{MAC: mac}) },
+			"AES/CTR/iv": func() (*Crypt, error) { return NewAES(randBytes(16), randBytes(16), Options{Mode: MODE_CTR, MAC: mac}) },
+			"AES/ECB":    func() (*Crypt, error) { return NewAES(randBytes(32), nil, Options{Mode: MODE_ECB, MAC: mac}) },
+			"DES/OFB":    func() (*Crypt, error) { return NewDES(testPassword, nil, Options{Mode: MODE_OFB, MAC: mac}) },
+			"DES3/CFB": func() (*Crypt, error) {
+				return NewDES3(testPassword, nil, Options{Mode: MODE_CFB, MAC: mac, KDF: Scrypt{N: 1 << 10}})
+			},
+			"ChaCha20": func() (*Crypt, error) { return NewChaCha20(testPassword, nil, Options{MAC: mac}) },
+		}
+		for name, fn := range crypts {
+			name = mac.String() + "/" + name
+			c, err := fn()
+			if err != nil {
+				t.Fatal(name, err)
+			}
+			ciphertext, err := c.Encrypt(text)
+			if err != nil {
+				t.Fatal(name, err)
+			}
+			plaintext, err := c.Decrypt(ciphertext)
+			if err != nil {
+				t.Fatal(name, err)
+			}
+			if !bytes.Equal(plaintext, text) {
+				t.Fatalf("%s: wrong plaintext %q", name, plaintext)
+			}
+			for _, i := range []int{0, 9, len(ciphertext) - mac.Size() - 1, len(ciphertext) - 1} {
+				tampered := append([]byte{}, ciphertext...)
+				tampered[i] ^= 1
+				// a modified header may already fail to parse
+				if _, err = c.Decrypt(tampered); err == nil || (i > 32 && !errors.Is(err, ErrAuthentication)) {
+					t.Fatalf("%s: byte %d: expected ErrAuthentication, got %v", name, i, err)
+				}
+			}
+			if _, err = c.Decrypt(ciphertext[:mac.Size()-1]); !errors.Is(err, ErrAuthentication) {
+				t.Fatalf("%s: expected ErrAuthentication for a short ciphertext, got %v", name, err)
+			}
+
+			var buf bytes.Buffer
+			w, err := c.NewEncryptWriter(&buf)
+			if err != nil {
+				t.Fatal(name, err)
+			}
+			w.Write(text)
+			if err = w.Close(); err != nil {
+				t.Fatal(name, err)
+			}
+			r, err := c.NewDecryptReader(&buf)
+			if err != nil {
+				t.Fatal(name, err)
+			}
+			if plaintext, err = io.ReadAll(r); err != nil || !bytes.Equal(plaintext, text) {
+				t.Fatal(name, "stream round trip failed", err)
+			}
+		}
+	}
+	if _, err := NewAES(randBytes(16), nil, Options{Mode: MODE_GCM, MAC: MAC_HMAC_SHA256}); err == nil {
+		t.Fatal("expected error for MAC with GCM")
+	}
+}
+
+func TestCMAC(t *testing.T) {
+	// RFC 4493 section 4
+	key, _ := hex.DecodeString("2b7e151628aed2a6abf7158809cf4f3c")
+	msg, _ := hex.DecodeString("6bc1bee22e409f96e93d7e117393172aae2d8a571e03ac9c9eb76fac45af8e5130c81c46a35ce411e5fbc1191a0a52eff69f2445df4f9b17ad2b417be66c3710")
+	var vectors = []struct {
+		size int
+		tag  string
+	}{
+		{0, "bb1d6929e95937287fa37d129b756746"},
+		{16, "070a16b46b4d4144f79bdd9dd04a287c"},
+		{40, "dfa66747de9ae63030ca32611497c827"},
+		{64, "51f0bebf7e3b9d92fc49741779363cfe"},
+	}
+	block, _ := aes.NewCipher(key)
+	m, err := ciphers.NewCMAC(block)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range vectors {
+		m.Reset()
+		for p := msg[:v.size]; len(p) > 0; p = p[len(p)/2+1:] {
+			m.Write(p[:len(p)/2+1])
+		}
+		if tag := hex.EncodeToString(m.Sum(nil)); tag != v.tag {
+			t.Fatalf("%d bytes: CMAC %s, want %s", v.size, tag, v.tag)
+		}
+	}
+}
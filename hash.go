@@ -0,0 +1,147 @@
+package crypt
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"hash"
+
+	"golang.org/x/crypto/blake2b"
+	"golang.org/x/crypto/blake2s"
+	"golang.org/x/crypto/ripemd160"
+	"golang.org/x/crypto/sha3"
+	"lukechampine.com/blake3"
+)
+
+// Hash is the shortcut of a hash function. The zero Hash has no hash
+// function, Sum returns nil, Hex and Base64 "" and New nil.
+type Hash struct {
+	new func() hash.Hash
+}
+
+// Sum returns the digest of data.
+func (h Hash) Sum(data []byte) []byte {
+	if h.new == nil {
+		return nil
+	}
+	d := h.new()
+	d.Write(data)
+	return d.Sum(nil)
+}
+
+// Hex returns the digest of data in lower case hex.
+func (h Hash) Hex(data []byte) string {
+	if h.new == nil {
+		return ""
+	}
+	return hex.EncodeToString(h.Sum(data))
+}
+
+// Base64 returns the digest of data in standard padded base64.
+func (h Hash) Base64(data []byte) string {
+	if h.new == nil {
+		return ""
+	}
+	return base64.StdEncoding.EncodeToString(h.Sum(data))
+}
+
+// New returns a streaming hash.
+func (h Hash) New() hash.Hash {
+	if h.new == nil {
+		return nil
+	}
+	return h.new()
+}
+
+var (
+	SHA1       = Hash{new: sha1.New}
+	SHA224     = Hash{new: sha256.New224}
+	SHA256     = Hash{new: sha256.New}
+	SHA384     = Hash{new: sha512.New384}
+	SHA512     = Hash{new: sha512.New}
+	SHA512_256 = Hash{new: sha512.New512_256}
+	SHA3_224   = Hash{new: sha3.New224}
+	SHA3_256   = Hash{new: sha3.New256}
+	SHA3_384   = Hash{new: sha3.New384}
+	SHA3_512   = Hash{new: sha3.New512}
+	RIPEMD160  = Hash{new: ripemd160.New}
+	// SM3 is the Chinese national standard hash GB/T 32905-2016.
+	SM3 = Hash{new: newSM3}
+)
+
+// BLAKE2b is BLAKE2b-512. With returns other sizes and keyed BLAKE2b.
+var BLAKE2b = cryptBLAKE2b{Hash{new: func() hash.Hash {
+	d, _ := blake2b.New512(nil)
+	return d
+}}}
+
+type cryptBLAKE2b struct{ Hash }
+
+// With returns BLAKE2b of size bytes, 1 to 64, keyed with a key of up to 64
+// bytes, or unkeyed if key is nil.
+func (cryptBLAKE2b) With(size int, key []byte) (Hash, error) {
+	if _, err := blake2b.New(size, key); err != nil {
+		return Hash{}, fmt.Errorf("crypt BLAKE2b: %w", err)
+	}
+	key = append([]byte(nil), key...)
+	return Hash{new: func() hash.Hash {
+		d, _ := blake2b.New(size, key)
+		return d
+	}}, nil
+}
+
+// BLAKE2s is BLAKE2s-256. With returns keyed BLAKE2s.
+var BLAKE2s = cryptBLAKE2s{Hash{new: func() hash.Hash {
+	d, _ := blake2s.New256(nil)
+	return d
+}}}
+
+type cryptBLAKE2s struct{ Hash }
+
+// With returns BLAKE2s of size bytes keyed with a key of up to 32 bytes, or
+// unkeyed if key is nil. The size is 32, or 16 with a key, the only sizes
+// golang.org/x/crypto/blake2s has.
+func (cryptBLAKE2s) With(size int, key []byte) (Hash, error) {
+	var newHash func(key []byte) (hash.Hash, error)
+	switch size {
+	case blake2s.Size:
+		newHash = blake2s.New256
+	case blake2s.Size128:
+		newHash = blake2s.New128
+	default:
+		return Hash{}, fmt.Errorf("crypt BLAKE2s: invalid size %d, must be 16 or 32", size)
+	}
+	if _, err := newHash(key); err != nil {
+		return Hash{}, fmt.Errorf("crypt BLAKE2s: %w", err)
+	}
+	key = append([]byte(nil), key...)
+	return Hash{new: func() hash.Hash {
+		d, _ := newHash(key)
+		return d
+	}}, nil
+}
+
+// BLAKE3 is BLAKE3 with a 32 byte digest. With returns other sizes and keyed
+// BLAKE3.
+var BLAKE3 = cryptBLAKE3{Hash{new: func() hash.Hash {
+	return blake3.New(32, nil)
+}}}
+
+type cryptBLAKE3 struct{ Hash }
+
+// With returns BLAKE3 of size bytes, any size from 1 since BLAKE3 is
+// extendable, keyed with a 32 byte key, or unkeyed if key is nil.
+func (cryptBLAKE3) With(size int, key []byte) (Hash, error) {
+	if size < 1 {
+		return Hash{}, fmt.Errorf("crypt BLAKE3: invalid size %d", size)
+	} else if key != nil && len(key) != 32 {
+		return Hash{}, fmt.Errorf("crypt BLAKE3: invalid key size %d, must be 32", len(key))
+	}
+	key = append([]byte(nil), key...)
+	return Hash{new: func() hash.Hash {
+		return blake3.New(size, key)
+	}}, nil
+}
@@ -0,0 +1,132 @@
+package crypt
+
+import (
+	"bytes"
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+func TestHash(t *testing.T) {
+	// made with OpenSSL 3 and Python hashlib, SM3 is GB/T 32905-2016 A.1 and A.2
+	var abc = []byte("abc")
+	var vectors = []struct {
+		name   string
+		h      Hash
+		data   []byte
+		digest string
+	}{
+		{"SHA1", SHA1, abc, "a9993e364706816aba3e25717850c26c9cd0d89d"},
+		{"SHA224", SHA224, abc, "23097d223405d8228642a477bda255b32aadbce4bda0b3f7e36c9da7"},
+		{"SHA256", SHA256, abc, "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"},
+		{"SHA384", SHA384, abc, "cb00753f45a35e8bb5a03d699ac65007272c32ab0eded1631a8b605a43ff5bed8086072ba1e7cc2358baeca134c825a7"},
+		{"SHA512", SHA512, abc, "ddaf35a193617abacc417349ae20413112e6fa4e89a97ea20a9eeee64b55d39a2192992a274fc1a836ba3c23a3feebbd454d4423643ce80e2a9ac94fa54ca49f"},
+		{"SHA512_256", SHA512_256, abc, "53048e2681941ef99b2e29b76b4c7dabe4c2d0c634fc6d46e0e2f13107e7af23"},
+		{"SHA3_224", SHA3_224, abc, "e642824c3f8cf24ad09234ee7d3c766fc9a3a5168d0c94ad73b46fdf"},
+		{"SHA3_256", SHA3_256, abc, "3a985da74fe225b2045c172d6bd390bd855f086e3e9d525b46bfe24511431532"},
+		{"SHA3_384", SHA3_384, abc, "ec01498288516fc926459f58e2c6ad8df9b473cb0fc08c2596da7cf0e49be4b298d88cea927ac7f539f1edf228376d25"},
+		{"SHA3_512", SHA3_512, abc, "b751850b1a57168a5693cd924b6b096e08f621827444f70d884f5d0240d2712e10e116e9192af3c91a7ec57647e3934057340b4cf408d5a56592f8274eec53f0"},
+		{"RIPEMD160", RIPEMD160, abc, "8eb208f7e05d987a9b044a8e98c6b087f15a0bfc"},
+		{"SM3", SM3, abc, "66c7f0f462eeedd9d1f2d46bdc10e4e24167c4875cf2f7a2297da02b8f4ba8e0"},
+		{"SM3", SM3, []byte(strings.Repeat("abcd", 16)), "debe9ff92275b8a138604889c18e5a4d6fdb70e5387e5765293dcba39c0c5732"},
+		{"SM3", SM3, []byte(strings.Repeat("a", 1000)), "f4bedca973227d45c5b822551d2e762d4cfb0e9af70b241452545727b5fb046f"},
+		{"BLAKE2b", BLAKE2b.Hash, abc, "ba80a53f981c4d0d6a2797b69f12f6e94c212f14685ac4b74b12bb6fdbffa2d17d87c5392aab792dc252d5de4533cc9518d38aa8dbf1925ab92386edd4009923"},
+		{"BLAKE2s", BLAKE2s.Hash, abc, "508c5e8c327c14e2e1a72ba34eeb452f37458b209ed63a294d999b4c86675982"},
+		{"BLAKE3", BLAKE3.Hash, abc, "6437b3ac38465133ffb63b75273a8db548c558465d79db03fd359c6cd5bd9d85"},
+	}
+	for _, v := range vectors {
+		if digest := v.h.Hex(v.data); digest != v.digest {
+			t.Fatalf("%s: %s", v.name, digest)
+		}
+		// streaming in uneven pieces must give the same digest
+		var d = v.h.New()
+		for p := v.data; len(p) > 0; {
+			n := len(p)
+			if n > 7 {
+				n = 7
+			}
+			d.Write(p[:n])
+			p = p[n:]
+		}
+		if hex.EncodeToString(d.Sum(nil)) != v.digest {
+			t.Fatalf("%s: New differs", v.name)
+		}
+	}
+
+	if SHA256.Base64(abc) != "ungWv48Bz+pBQUDeXa4iI7ADYaOWF3qctBD/YfIAFa0=" {
+		t.Fatal(SHA256.Base64(abc))
+	}
+	if !bytes.Equal(SHA3_256.Sum(abc), SHA3.Sum256(abc)) {
+		t.Fatal("SHA3_256 differs from SHA3.Sum256")
+	}
+	if MD5.Base64(abc) != "kAFQmDzST7DWlj99KOF/cg==" {
+		t.Fatal(MD5.Base64(abc))
+	}
+	var d = MD5.New()
+	d.Write(abc)
+	if !bytes.Equal(d.Sum(nil), MD5.Sum(abc)) {
+		t.Fatal("MD5.New differs")
+	}
+
+	// the zero Hash must not panic
+	var zero Hash
+	if zero.Sum(abc) != nil || zero.Hex(abc) != "" || zero.Base64(abc) != "" || zero.New() != nil {
+		t.Fatal("zero Hash")
+	}
+}
+
+func TestHashWith(t *testing.T) {
+	var key = []byte("secret key")
+	var vectors = []struct {
+		name   string
+		with   func(int, []byte) (Hash, error)
+		size   int
+		key    []byte
+		digest string
+	}{
+		{"BLAKE2b", BLAKE2b.With, 32, key, "66c28e9d1dcd69d6756fc52125fe1838cf0c6a87d058545a9ff676bf51beaa6f"},
+		{"BLAKE2s", BLAKE2s.With, 16, key, "1c4572c125284d4d3f6b4c525d26e0e0"},
+		// the BLAKE3 test vectors keyed_hash of the empty input
+		{"BLAKE3", BLAKE3.With, 32, []byte("whats the Elvish word for friend"), "92b2b75604ed3c761f9d6f62392c8a9227ad0ea3f09573e783f1498a4ed60d26"},
+	}
+	for _, v := range vectors {
+		h, err := v.with(v.size, v.key)
+		if err != nil {
+			t.Fatal(v.name, err)
+		}
+		var data = []byte("abc")
+		if v.name == "BLAKE3" {
+			data = nil
+		}
+		if digest := h.Hex(data); digest != v.digest {
+			t.Fatalf("%s: %s", v.name, digest)
+		}
+	}
+
+	// BLAKE3 is extendable, a longer digest starts with the shorter one
+	long, _ := BLAKE3.With(100, nil)
+	if digest := long.Sum([]byte("abc")); len(digest) != 100 || !bytes.Equal(digest[:32], BLAKE3.Sum([]byte("abc"))) {
+		t.Fatalf("BLAKE3 With(100): %x", digest)
+	}
+
+	var invalid = []struct {
+		name string
+		with func(int, []byte) (Hash, error)
+		size int
+		key  []byte
+	}{
+		{"BLAKE2b", BLAKE2b.With, 0, nil},
+		{"BLAKE2b", BLAKE2b.With, 65, nil},
+		{"BLAKE2b", BLAKE2b.With, 32, make([]byte, 65)},
+		{"BLAKE2s", BLAKE2s.With, 20, nil},
+		{"BLAKE2s", BLAKE2s.With, 16, nil},
+		{"BLAKE2s", BLAKE2s.With, 32, make([]byte, 33)},
+		{"BLAKE3", BLAKE3.With, 0, nil},
+		{"BLAKE3", BLAKE3.With, 32, key},
+	}
+	for _, v := range invalid {
+		if _, err := v.with(v.size, v.key); err == nil {
+			t.Fatalf("%s: expected an error for size %d and a %d byte key", v.name, v.size, len(v.key))
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package crypt
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"fmt"
+	"hash"
+
+	"golang.org/x/crypto/sha3"
+)
+
+var HMAC cryptHMAC
+
+type cryptHMAC struct{}
+
+// MD5 returns the HMAC-MD5 of data.
+func (cryptHMAC) MD5(data, key []byte) []byte {
+	return hmacSum(md5.New, data, key)
+}
+
+// SHA1 returns the HMAC-SHA1 of data.
+func (cryptHMAC) SHA1(data, key []byte) []byte {
+	return hmacSum(sha1.New, data, key)
+}
+
+// SHA256 returns the HMAC-SHA256 of data.
+func (cryptHMAC) SHA256(data, key []byte) []byte {
+	return hmacSum(sha256.New, data, key)
+}
+
+// SHA384 returns the HMAC-SHA384 of data.
+func (cryptHMAC) SHA384(data, key []byte) []byte {
+	return hmacSum(sha512.New384, data, key)
+}
+
+// SHA512 returns the HMAC-SHA512 of data.
+func (cryptHMAC) SHA512(data, key []byte) []byte {
+	return hmacSum(sha512.New, data, key)
+}
+
+// SHA3_224 returns the HMAC-SHA3-224 of data.
+func (cryptHMAC) SHA3_224(data, key []byte) []byte {
+	return hmacSum(sha3.New224, data, key)
+}
+
+// SHA3_256 returns the HMAC-SHA3-256 of data.
+func (cryptHMAC) SHA3_256(data, key []byte) []byte {
+	return hmacSum(sha3.New256, data, key)
+}
+
+// SHA3_384 returns the HMAC-SHA3-384 of data.
+func (cryptHMAC) SHA3_384(data, key []byte) []byte {
+	return hmacSum(sha3.New384, data, key)
+}
+
+// SHA3_512 returns the HMAC-SHA3-512 of data.
+func (cryptHMAC) SHA3_512(data, key []byte) []byte {
+	return hmacSum(sha3.New512, data, key)
+}
+
+// New returns a streaming HMAC with the hash function h, such as crypto.SHA256.
+func (cryptHMAC) New(h crypto.Hash, key []byte) (hash.Hash, error) {
+	if !h.Available() {
+		return nil, fmt.Errorf("crypt HMAC: hash function %s is not available", h)
+	}
+	return hmac.New(h.New, key), nil
+}
+
+func hmacSum(h func() hash.Hash, data, key []byte) []byte {
+	m := hmac.New(h, key)
+	m.Write(data)
+	return m.Sum(nil)
+}
+
+// Verify reports whether mac and expected are equal in constant time. Compare
+// MACs with it rather than bytes.Equal, whose timing tells an attacker how many
+// leading bytes of a forged MAC are right.
+func Verify(mac, expected []byte) bool {
+	return hmac.Equal(mac, expected)
+}
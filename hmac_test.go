@@ -0,0 +1,102 @@
+package crypt
+
+import (
+	"crypto"
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+func TestHMAC(t *testing.T) {
+	// RFC 2202, RFC 4231 test case 2 and OpenSSL for SHA-3
+	var key, data = []byte("Jefe"), []byte("what do ya want for nothing?")
+	var vectors = []struct {
+		hash crypto.Hash
+		sum  func(data, key []byte) []byte
+		mac  string
+	}{
+		{crypto.MD5, HMAC.MD5, "750c783e6ab0b503eaa86e310a5db738"},
+		{crypto.SHA1, HMAC.SHA1, "effcdf6ae5eb2fa2d27416d5f184df9c259a7c79"},
+		{crypto.SHA256, HMAC.SHA256, "5bdcc146bf60754e6a042426089575c75a003f089d2739839dec58b964ec3843"},
+		{crypto.SHA384, HMAC.SHA384, "af45d2e376484031617f78d2b58a6b1b9c7ef464f5a01b47e42ec3736322445e8e2240ca5e69e2c78b3239ecfab21649"},
+		{crypto.SHA512, HMAC.SHA512, "164b7a7bfcf819e2e395fbe73b56e0a387bd64222e831fd610270cd7ea2505549758bf75c05a994a6d034f65f8f0e6fdcaeab1a34d4a6b4b636e070a38bce737"},
+		{crypto.SHA3_224, HMAC.SHA3_224, "7fdb8dd88bd2f60d1b798634ad386811c2cfc85bfaf5d52bbace5e66"},
+		{crypto.SHA3_256, HMAC.SHA3_256, "c7d4072e788877ae3596bbb0da73b887c9171f93095b294ae857fbe2645e1ba5"},
+		{crypto.SHA3_384, HMAC.SHA3_384, "f1101f8cbf9766fd6764d2ed61903f21ca9b18f57cf3e1a23ca13508a93243ce48c045dc007f26a21b3f5e0e9df4c20a"},
+		{crypto.SHA3_512, HMAC.SHA3_512, "5a4bfeab6166427c7a3647b747292b8384537cdb89afb3bf5665e4c5e709350b287baec921fd7ca0ee7a0c31d022a95e1fc92ba9d77df883960275beb4e62024"},
+	}
+	for _, v := range vectors {
+		mac := v.sum(data, key)
+		if hex.EncodeToString(mac) != v.mac {
+			t.Fatalf("HMAC-%s: %x", v.hash, mac)
+		}
+		h, err := HMAC.New(v.hash, key)
+		if err != nil {
+			t.Fatal(v.hash, err)
+		}
+		h.Write(data[:10])
+		h.Write(data[10:])
+		if !Verify(h.Sum(nil), mac) {
+			t.Fatalf("HMAC-%s: New differs", v.hash)
+		}
+	}
+	if Verify(HMAC.SHA256(data, key), HMAC.SHA256(data, []byte("jefe"))) {
+		t.Fatal("Verify accepted a different MAC")
+	}
+	if Verify(HMAC.SHA256(data, key)[:16], HMAC.SHA256(data, key)) {
+		t.Fatal("Verify accepted a truncated MAC")
+	}
+	if _, err := HMAC.New(crypto.Hash(0), key); err == nil {
+		t.Fatal("expected an error for an unavailable hash")
+	}
+}
+
+func TestKMAC(t *testing.T) {
+	// NIST SP 800-185 KMAC samples 1, 2, 4 and 5
+	var key, _ = hex.DecodeString("404142434445464748494a4b4c4d4e4f505152535455565758595a5b5c5d5e5f")
+	var short = []byte{0, 1, 2, 3}
+	var long = make([]byte, 200)
+	for i := range long {
+		long[i] = byte(i)
+	}
+	var tagged = []byte("My Tagged Application")
+	var vectors = []struct {
+		sum           func(data, key, customization []byte, size int) ([]byte, error)
+		data          []byte
+		customization []byte
+		mac           string
+	}{
+		{KMAC.Sum128, short, nil, "e5780b0d3ea6f7d3a429c5706aa43a00fadbd7d49628839e3187243f456ee14e"},
+		{KMAC.Sum128, short, tagged, "3b1fba963cd8b0b59e8c1a6d71888b7143651af8ba0a7070c0979e2811324aa5"},
+		{KMAC.Sum256, short, tagged, "20c570c31346f703c9ac36c61c03cb64c3970d0cfc787e9b79599d273a68d2f7f69d4cc3de9d104a351689f27cf6f5951f0103f33f4f24871024d9c27773a8dd"},
+		{KMAC.Sum256, long, nil, "75358cf39e41494e949707927cee0af20a3ff553904c86b08f21cc414bcfd691589d27cf5e15369cbbff8b9a4c2eb17800855d0235ff635da82533ec6b759b69"},
+	}
+	for i, v := range vectors {
+		if mac, err := v.sum(v.data, key, v.customization, len(v.mac)/2); err != nil || hex.EncodeToString(mac) != v.mac {
+			t.Fatalf("%d: %x, %v", i, mac, err)
+		}
+	}
+
+	// L = 0 is allowed, a negative size is not
+	if mac, err := KMAC.Sum128(short, key, nil, 0); err != nil || len(mac) != 0 {
+		t.Fatalf("size 0: %x, %v", mac, err)
+	}
+	if _, err := KMAC.Sum256(short, key, nil, -1); err == nil {
+		t.Fatal("negative size")
+	}
+	if _, err := KMAC.New128(key, nil, -1); err == nil {
+		t.Fatal("New128 negative size")
+	}
+
+	h, err := KMAC.New256(key, nil, 64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.Write([]byte(strings.Repeat("x", 300)))
+	h.Reset()
+	h.Write(long[:100])
+	h.Write(long[100:])
+	if mac := h.Sum(nil); hex.EncodeToString(mac) != vectors[3].mac || h.Size() != 64 || h.BlockSize() != 136 {
+		t.Fatalf("New256: %x", mac)
+	}
+}
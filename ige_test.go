@@ -0,0 +1,65 @@
+package crypt
+
+import (
+	"bytes"
+	"encoding/hex"
+	"io"
+	"testing"
+)
+
+func TestIGE(t *testing.T) {
+	// OpenSSL IGE test vectors
+	var vectors = []struct {
+		key, iv, plaintext, ciphertext string
+	}{
+		{"000102030405060708090a0b0c0d0e0f", "000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f", "0000000000000000000000000000000000000000000000000000000000000000", "1a8519a6557be652e9da8e43da4ef4453cf456b4ca488aa383c79c98b34797cb"},
+		{"5468697320697320616e20696d706c65", "6d656e746174696f6e206f6620494745206d6f646520666f72204f70656e5353", "99706487a1cde613bc6de0b6f24b1c7aa448c8b9c3403e3467a8cad89340f53b", "4c2e204c6574277320686f70652042656e20676f74206974207269676874210a"},
+	}
+	for i, v := range vectors {
+		key, _ := hex.DecodeString(v.key)
+		iv, _ := hex.DecodeString(v.iv)
+		plaintext, _ := hex.DecodeString(v.plaintext)
+		ciphertext, err := AES.Encrypt(plaintext, key, iv, Options{Mode: MODE_IGE, Padding: PAD_NOPADDING})
+		if err != nil {
+			t.Fatal(i, err)
+		}
+		if hex.EncodeToString(ciphertext) != v.ciphertext {
+			t.Fatalf("%d: %x", i, ciphertext)
+		}
+		if decrypted, err := AES.Decrypt(ciphertext, key, iv, Options{Mode: MODE_IGE, Padding: PAD_NOPADDING}); err != nil || !bytes.Equal(decrypted, plaintext) {
+			t.Fatalf("%d: decrypt %x, %v", i, decrypted, err)
+		}
+	}
+	if _, err := NewAES(randBytes(16), randBytes(16), Options{Mode: MODE_IGE}); err == nil {
+		t.Fatal("IGE accepted a one block IV")
+	}
+	testModeStream(t, MODE_IGE, []byte("Kerberos v4 ticket, 3 blocks"))
+}
+
+// testModeStream checks that mode gives the same plaintext from Decrypt and
+// the streams for text written in two pieces.
+func testModeStream(t *testing.T, mode BlockMode, text []byte) {
+	c, err := NewAES(testPassword, nil, Options{Mode: mode})
+	if err != nil {
+		t.Fatal(mode, err)
+	}
+	var buf bytes.Buffer
+	w, err := c.NewEncryptWriter(&buf)
+	if err != nil {
+		t.Fatal(mode, err)
+	}
+	w.Write(text)
+	w.Write(text)
+	w.Close()
+	decrypted, err := c.Decrypt(buf.Bytes())
+	if err != nil || !bytes.Equal(decrypted, append(append([]byte{}, text...), text...)) {
+		t.Fatalf("%s: %q, %v", mode, decrypted, err)
+	}
+	r, err := c.NewDecryptReader(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(mode, err)
+	}
+	if streamed, err := io.ReadAll(r); err != nil || !bytes.Equal(streamed, decrypted) {
+		t.Fatalf("%s: stream %q, %v", mode, streamed, err)
+	}
+}
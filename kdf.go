@@ -0,0 +1,406 @@
+package crypt
+
+import (
+	"bytes"
+	"crypto"
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/hkdf"
+	"golang.org/x/crypto/pbkdf2"
+	"golang.org/x/crypto/scrypt"
+)
+
+// Versioned header written instead of the salted header when Options.KDF is set.
+//
+//	0  5  "Crypt"
+//	5  1  header version
+//	6  1  KDF ID
+//	7  1  salt length
+//	8  2  KDF parameters length, big endian
+//	10 n  KDF parameters, see KDF.MarshalBinary
+//	.. m  salt
+const (
+	kdfHeaderMagic   = "Crypt"
+	kdfHeaderVersion = 1
+	kdfHeaderSize    = 10
+	kdfSaltByteSize  = 16
+)
+
+// Upper bounds of the KDF parameters. They are read from the ciphertext
+// header, so a crafted header could otherwise make Decrypt run for hours or
+// allocate terabytes.
+const (
+	maxPBKDF2Iterations = 10000000
+	maxScryptP          = 16
+	maxArgon2idTime     = 16
+	// maxKDFMemory is the memory of scrypt and Argon2id, 1 GiB.
+	maxKDFMemory = 1 << 30
+)
+
+type KDFID uint8
+
+const (
+	KDF_PBKDF2 KDFID = iota + 1
+	KDF_SCRYPT
+	KDF_ARGON2ID
+	KDF_HKDF
+)
+
+func (id KDFID) String() string {
+	switch id {
+	case KDF_PBKDF2:
+		return "PBKDF2"
+	case KDF_SCRYPT:
+		return "scrypt"
+	case KDF_ARGON2ID:
+		return "Argon2id"
+	case KDF_HKDF:
+		return "HKDF"
+	}
+	return ""
+}
+
+// KDF derives the key and IV from the password when no IV is given. The KDF and
+// its parameters are recorded in the ciphertext header, so decrypting does not
+// need Options.KDF.
+type KDF interface {
+	ID() KDFID
+	// DeriveKey returns size bytes derived from password and salt.
+	DeriveKey(password, salt []byte, size int) ([]byte, error)
+	// MarshalBinary encodes the parameters stored in the header.
+	MarshalBinary() ([]byte, error)
+}
+
+// PBKDF2 is PBKDF2 (RFC 8018) with HMAC-SHA256 or HMAC-SHA512.
+type PBKDF2 struct {
+	// Hash is crypto.SHA256 or crypto.SHA512, default crypto.SHA256.
+	Hash crypto.Hash
+	// Iterations defaults to 600000, at most 10000000.
+	Iterations int
+}
+
+func (PBKDF2) ID() KDFID { return KDF_PBKDF2 }
+
+func (k PBKDF2) DeriveKey(password, salt []byte, size int) ([]byte, error) {
+	k = k.withDefaults()
+	if k.Hash != crypto.SHA256 && k.Hash != crypto.SHA512 {
+		return nil, fmt.Errorf("crypt PBKDF2: unsupported hash %s", k.Hash)
+	}
+	if err := k.check(); err != nil {
+		return nil, err
+	}
+	return pbkdf2.Key(password, salt, k.Iterations, size, k.Hash.New), nil
+}
+
+func (k PBKDF2) MarshalBinary() ([]byte, error) {
+	k = k.withDefaults()
+	if err := k.check(); err != nil {
+		return nil, err
+	}
+	var params = make([]byte, 5)
+	params[0] = byte(k.Hash)
+	binary.BigEndian.PutUint32(params[1:], uint32(k.Iterations))
+	return params, nil
+}
+
+func (k PBKDF2) check() error {
+	if k.Iterations < 1 || k.Iterations > maxPBKDF2Iterations {
+		return fmt.Errorf("crypt PBKDF2: invalid iterations %d", k.Iterations)
+	}
+	return nil
+}
+
+func (k PBKDF2) withDefaults() PBKDF2 {
+	if k.Hash == 0 {
+		k.Hash = crypto.SHA256
+	}
+	if k.Iterations == 0 {
+		k.Iterations = 600000
+	}
+	return k
+}
+
+// Scrypt is scrypt (RFC 7914). The memory used, 128 * N * R bytes, is at most
+// 1 GiB.
+type Scrypt struct {
+	// N is the CPU/memory cost, a power of two, default 1<<15.
+	N int
+	// R is the block size, default 8.
+	R int
+	// P is the parallelization, default 1, at most 16.
+	P int
+}
+
+func (Scrypt) ID() KDFID { return KDF_SCRYPT }
+
+func (k Scrypt) DeriveKey(password, salt []byte, size int) ([]byte, error) {
+	k = k.withDefaults()
+	if err := k.check(); err != nil {
+		return nil, err
+	}
+	return scrypt.Key(password, salt, k.N, k.R, k.P, size)
+}
+
+func (k Scrypt) MarshalBinary() ([]byte, error) {
+	k = k.withDefaults()
+	if err := k.check(); err != nil {
+		return nil, err
+	}
+	var params = make([]byte, 12)
+	binary.BigEndian.PutUint32(params, uint32(k.N))
+	binary.BigEndian.PutUint32(params[4:], uint32(k.R))
+	binary.BigEndian.PutUint32(params[8:], uint32(k.P))
+	return params, nil
+}
+
+func (k Scrypt) check() error {
+	if k.N < 2 || k.R < 1 || k.N > maxKDFMemory/128/k.R {
+		return fmt.Errorf("crypt scrypt: invalid N %d or R %d", k.N, k.R)
+	} else if k.P < 1 || k.P > maxScryptP {
+		return fmt.Errorf("crypt scrypt: invalid P %d", k.P)
+	}
+	return nil
+}
+
+func (k Scrypt) withDefaults() Scrypt {
+	if k.N == 0 {
+		k.N = 1 << 15
+	}
+	if k.R == 0 {
+		k.R = 8
+	}
+	if k.P == 0 {
+		k.P = 1
+	}
+	return k
+}
+
+// Argon2id is Argon2id (RFC 9106).
+type Argon2id struct {
+	// Time is the number of passes, default 3, at most 16.
+	Time uint32
+	// Memory is the memory size in KiB, default 64 MiB, at most 1 GiB.
+	Memory uint32
+	// Threads defaults to 4.
+	Threads uint8
+}
+
+func (Argon2id) ID() KDFID { return KDF_ARGON2ID }
+
+func (k Argon2id) DeriveKey(password, salt []byte, size int) ([]byte, error) {
+	k = k.withDefaults()
+	if err := k.check(); err != nil {
+		return nil, err
+	}
+	return argon2.IDKey(password, salt, k.Time, k.Memory, k.Threads, uint32(size)), nil
+}
+
+func (k Argon2id) MarshalBinary() ([]byte, error) {
+	k = k.withDefaults()
+	if err := k.check(); err != nil {
+		return nil, err
+	}
+	var params = make([]byte, 9)
+	binary.BigEndian.PutUint32(params, k.Time)
+	binary.BigEndian.PutUint32(params[4:], k.Memory)
+	params[8] = k.Threads
+	return params, nil
+}
+
+func (k Argon2id) check() error {
+	if k.Time > maxArgon2idTime {
+		return fmt.Errorf("crypt Argon2id: invalid time %d", k.Time)
+	} else if k.Memory > maxKDFMemory/1024 {
+		return fmt.Errorf("crypt Argon2id: invalid memory %d KiB", k.Memory)
+	}
+	return nil
+}
+
+func (k Argon2id) withDefaults() Argon2id {
+	if k.Time == 0 {
+		k.Time = 3
+	}
+	if k.Memory == 0 {
+		k.Memory = 64 * 1024
+	}
+	if k.Threads == 0 {
+		k.Threads = 4
+	}
+	return k
+}
+
+// HKDF is HKDF (RFC 5869). It is not a password hash, only use it when the
+// "password" is already a high entropy key.
+type HKDF struct {
+	// Hash is crypto.SHA256 or crypto.SHA512, default crypto.SHA256.
+	Hash crypto.Hash
+	// Info is stored in the header.
+	Info []byte
+}
+
+func (HKDF) ID() KDFID { return KDF_HKDF }
+
+func (k HKDF) DeriveKey(password, salt []byte, size int) ([]byte, error) {
+	k = k.withDefaults()
+	if k.Hash != crypto.SHA256 && k.Hash != crypto.SHA512 {
+		return nil, fmt.Errorf("crypt HKDF: unsupported hash %s", k.Hash)
+	}
+	var key = make([]byte, size)
+	if _, err := io.ReadFull(hkdf.New(k.Hash.New, password, salt, k.Info), key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+func (k HKDF) MarshalBinary() ([]byte, error) {
+	k = k.withDefaults()
+	return append([]byte{byte(k.Hash)}, k.Info...), nil
+}
+
+func (k HKDF) withDefaults() HKDF {
+	if k.Hash == 0 {
+		k.Hash = crypto.SHA256
+	}
+	return k
+}
+
+func unmarshalKDF(id KDFID, params []byte) (KDF, error) {
+	switch id {
+	case KDF_PBKDF2:
+		if len(params) == 5 {
+			return PBKDF2{Hash: crypto.Hash(params[0]), Iterations: int(binary.BigEndian.Uint32(params[1:]))}, nil
+		}
+	case KDF_SCRYPT:
+		if len(params) == 12 {
+			return Scrypt{
+				N: int(binary.BigEndian.Uint32(params)),
+				R: int(binary.BigEndian.Uint32(params[4:])),
+				P: int(binary.BigEndian.Uint32(params[8:])),
+			}, nil
+		}
+	case KDF_ARGON2ID:
+		if len(params) == 9 {
+			return Argon2id{
+				Time:    binary.BigEndian.Uint32(params),
+				Memory:  binary.BigEndian.Uint32(params[4:]),
+				Threads: params[8],
+			}, nil
+		}
+	case KDF_HKDF:
+		if len(params) >= 1 {
+			return HKDF{Hash: crypto.Hash(params[0]), Info: append([]byte{}, params[1:]...)}, nil
+		}
+	default:
+		return nil, fmt.Errorf("crypt: unknown KDF %d", id)
+	}
+	return nil, fmt.Errorf("crypt %s: invalid parameters", id)
+}
+
+// derivedIVSize returns the size of the IV derived along with the key by the
+// unauthenticated block modes.
+func derivedIVSize(blockSize int, mode BlockMode) int {
+	if mode == MODE_IGE {
+		return 2 * blockSize
+	} else if mode.Not(MODE_ECB) {
+		return blockSize
+	}
+	return 0
+}
+
+// genHeader returns the header of a password encrypted ciphertext with the key
+// and IV derived for it. Without a KDF it is the salted header, derived from
+// saltKey, the password as accepted by verifyKey.
+func genHeader(kdf KDF, saltKey, password []byte, ivSize, keySize int) (header, key, iv []byte, err error) {
+	if kdf == nil {
+		var salted [16]byte
+		salted, key, iv = genSaltHeader(saltKey, ivSize, keySize)
+		return salted[:], key, iv, nil
+	}
+	var params []byte
+	if params, err = kdf.MarshalBinary(); err != nil {
+		return nil, nil, nil, err
+	}
+	if len(params) > 0xffff {
+		return nil, nil, nil, fmt.Errorf("crypt %s: parameters too large", kdf.ID())
+	}
+	var salt = randBytes(kdfSaltByteSize)
+	header = make([]byte, kdfHeaderSize, kdfHeaderSize+len(params)+len(salt))
+	copy(header, kdfHeaderMagic)
+	header[5] = kdfHeaderVersion
+	header[6] = byte(kdf.ID())
+	header[7] = byte(len(salt))
+	binary.BigEndian.PutUint16(header[8:], uint16(len(params)))
+	header = append(append(header, params...), salt...)
+	key, iv, err = deriveKeyIV(kdf, password, salt, ivSize, keySize)
+	return
+}
+
+// parseHeader looks for a versioned or salted header at the start of src and
+// derives the key and IV from it. n is the length of the header, 0 if src does
+// not start with one.
+func parseHeader(src, saltKey, password []byte, ivSize, keySize int) (n int, key, iv []byte, err error) {
+	if salt, ok := getSalt(src); ok {
+		key, iv = parseSaltHeader(salt, saltKey, ivSize, keySize)
+		return 16, key, iv, nil
+	}
+	if n = kdfHeaderLen(src); n == 0 {
+		return 0, nil, nil, nil
+	} else if len(src) < n {
+		return 0, nil, nil, fmt.Errorf("crypt: truncated header")
+	}
+	var paramsSize = int(binary.BigEndian.Uint16(src[8:]))
+	kdf, err := unmarshalKDF(KDFID(src[6]), src[kdfHeaderSize:kdfHeaderSize+paramsSize])
+	if err != nil {
+		return 0, nil, nil, err
+	}
+	key, iv, err = deriveKeyIV(kdf, password, src[kdfHeaderSize+paramsSize:n], ivSize, keySize)
+	return
+}
+
+// kdfHeaderLen returns the length of the versioned header src starts with, or
+// 0 if it does not start with one.
+func kdfHeaderLen(src []byte) int {
+	if len(src) < kdfHeaderSize || !bytes.Equal(src[:5], []byte(kdfHeaderMagic)) || src[5] != kdfHeaderVersion {
+		return 0
+	}
+	return kdfHeaderSize + int(binary.BigEndian.Uint16(src[8:])) + int(src[7])
+}
+
+// readHeader reads the header r starts with, if any. The returned reader yields
+// the rest of the ciphertext.
+func readHeader(r io.Reader) (header []byte, rest io.Reader, err error) {
+	var head = make([]byte, 16)
+	n, err := io.ReadFull(r, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return nil, nil, err
+	}
+	head = head[:n]
+	if _, ok := getSalt(head); ok {
+		return head, r, nil
+	}
+	if size := kdfHeaderLen(head); size > 0 {
+		if size > n {
+			header = make([]byte, size)
+			copy(header, head)
+			if _, err = io.ReadFull(r, header[n:]); err != nil {
+				return nil, nil, fmt.Errorf("crypt: truncated header")
+			}
+			return header, r, nil
+		}
+		return head[:size], io.MultiReader(bytes.NewReader(head[size:]), r), nil
+	}
+	return nil, io.MultiReader(bytes.NewReader(head), r), nil
+}
+
+func deriveKeyIV(kdf KDF, password, salt []byte, ivSize, keySize int) (key, iv []byte, err error) {
+	var b []byte
+	if b, err = kdf.DeriveKey(password, salt, keySize+ivSize); err != nil {
+		return nil, nil, err
+	}
+	return b[:keySize], b[keySize:], nil
+}
@@ -0,0 +1,148 @@
+package crypt
+
+import (
+	"bytes"
+	"crypto"
+	"errors"
+	"io"
+	"testing"
+)
+
+// testPassword is long enough to be a key of every method, so it is a password
+// for the salted header without Options.KDF.
+var testPassword = []byte("correct horse battery staple 123")
+
+func TestKDF(t *testing.T) {
+	var password = []byte("password")
+	var text = []byte("hello kdf")
+	var kdfs = []KDF{
+		PBKDF2{Iterations: 1000},
+		PBKDF2{Hash: crypto.SHA512, Iterations: 1000},
+		Scrypt{N: 1 << 10},
+		Argon2id{Time: 1, Memory: 1024, Threads: 1},
+		HKDF{Info: []byte("crypt test")},
+	}
+	for _, kdf := range kdfs {
+		c, err := NewAES(password, nil, Options{KDF: kdf})
+		if err != nil {
+			t.Fatal(err)
+		}
+		ciphertext, err := c.Encrypt(text)
+		if err != nil {
+			t.Fatal(kdf.ID(), err)
+		}
+		if !bytes.HasPrefix(ciphertext, []byte(kdfHeaderMagic)) || KDFID(ciphertext[6]) != kdf.ID() {
+			t.Fatalf("%s: missing versioned header", kdf.ID())
+		}
+		// the KDF is read from the header, any Options.KDF makes the key a
+		// password
+		plaintext, err := AES.Decrypt(ciphertext, password, nil, Options{KDF: PBKDF2{}})
+		if err != nil {
+			t.Fatal(kdf.ID(), err)
+		}
+		if !bytes.Equal(plaintext, text) {
+			t.Fatalf("%s wrong", kdf.ID())
+		}
+		r, err := c.NewDecryptReader(bytes.NewReader(ciphertext))
+		if err != nil {
+			t.Fatal(kdf.ID(), err)
+		}
+		if plaintext, err = io.ReadAll(r); err != nil || !bytes.Equal(plaintext, text) {
+			t.Fatalf("%s: stream wrong: %v", kdf.ID(), err)
+		}
+		if plaintext, err = AES.Decrypt(ciphertext, []byte("passw0rd"), nil, Options{KDF: kdf}); err == nil && bytes.Equal(plaintext, text) {
+			t.Fatalf("%s: wrong password accepted", kdf.ID())
+		}
+	}
+
+	c, err := NewXChaCha20Poly1305(password, nil, Options{KDF: Scrypt{N: 1 << 10}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ciphertext := sealSegments(t, c, text)
+	if c, err = NewXChaCha20Poly1305(password, nil, Options{KDF: PBKDF2{}}); err != nil {
+		t.Fatal(err)
+	}
+	if plaintext, err := openSegments(c, ciphertext); err != nil || !bytes.Equal(plaintext, text) {
+		t.Fatalf("segmented stream with KDF wrong: %v", err)
+	}
+}
+
+func TestKDFPassword(t *testing.T) {
+	// without Options.KDF a key of the wrong size is an error, not a password
+	var keySizeErr *KeySizeError
+	if _, err := NewAES([]byte("password"), nil); !errors.As(err, &keySizeErr) {
+		t.Fatal("expected a KeySizeError without Options.KDF", err)
+	}
+	if _, err := NewChaCha20(make([]byte, 17), nil); !errors.As(err, &keySizeErr) {
+		t.Fatal("expected a KeySizeError without Options.KDF", err)
+	}
+	if _, err := NewAES([]byte("password"), randBytes(16), Options{KDF: PBKDF2{}}); !errors.As(err, &keySizeErr) {
+		t.Fatal("expected a KeySizeError with an IV", err)
+	}
+	if _, err := NewAES([]byte("password"), nil, Options{KDF: PBKDF2{}}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestKDFLimits(t *testing.T) {
+	var password = []byte("password")
+	var text = []byte("hello kdf")
+	// the parameters of a crafted header must not be derived with
+	var oversized = []struct {
+		kdf    KDF
+		params []byte
+	}{
+		{PBKDF2{Iterations: 1000}, []byte{byte(crypto.SHA256), 0xff, 0xff, 0xff, 0xff}},
+		{Scrypt{N: 1 << 10}, []byte{0x40, 0, 0, 0, 0, 0, 0, 8, 0, 0, 0, 1}},
+		{Scrypt{N: 1 << 10}, []byte{0, 0, 4, 0, 0, 0, 0, 8, 0xff, 0xff, 0xff, 0xff}},
+		{Argon2id{Time: 1, Memory: 1024, Threads: 1}, []byte{0, 0, 0, 1, 0xff, 0xff, 0xff, 0xff, 1}},
+		{Argon2id{Time: 1, Memory: 1024, Threads: 1}, []byte{0xff, 0xff, 0xff, 0xff, 0, 0, 4, 0, 1}},
+	}
+	for i, v := range oversized {
+		c, err := NewAES(password, nil, Options{Mode: MODE_CTR, KDF: v.kdf})
+		if err != nil {
+			t.Fatal(i, err)
+		}
+		ciphertext, err := c.Encrypt(text)
+		if err != nil {
+			t.Fatal(i, err)
+		}
+		copy(ciphertext[kdfHeaderSize:], v.params)
+		if _, err = c.Decrypt(ciphertext); err == nil {
+			t.Fatalf("%d: expected an error for oversized parameters", i)
+		}
+		if _, err = c.NewDecryptReader(bytes.NewReader(ciphertext)); err == nil {
+			t.Fatalf("%d: NewDecryptReader: expected an error for oversized parameters", i)
+		}
+		if _, err = c.DecryptRange(bytes.NewReader(ciphertext), 0, 1); err == nil {
+			t.Fatalf("%d: DecryptRange: expected an error for oversized parameters", i)
+		}
+
+		// the header is parsed before the MAC is verified
+		m, _ := NewAES(password, nil, Options{Mode: MODE_CTR, KDF: v.kdf, MAC: MAC_HMAC_SHA256})
+		if ciphertext, err = m.Encrypt(text); err != nil {
+			t.Fatal(i, err)
+		}
+		copy(ciphertext[kdfHeaderSize:], v.params)
+		if _, err = m.Decrypt(ciphertext); err == nil {
+			t.Fatalf("%d: Options.MAC: expected an error for oversized parameters", i)
+		}
+
+		if ciphertext, err = m.EncryptEnvelope(text); err != nil {
+			t.Fatal(i, err)
+		}
+		copy(ciphertext[envelopeSize:], v.params)
+		if _, err = Open(ciphertext, password); err == nil {
+			t.Fatalf("%d: Open: expected an error for oversized parameters", i)
+		}
+	}
+
+	for _, kdf := range []KDF{PBKDF2{Iterations: maxPBKDF2Iterations + 1}, Scrypt{N: 1 << 21}, Scrypt{P: 17}, Argon2id{Time: 17}, Argon2id{Memory: 2 << 20}} {
+		if _, err := NewAES(password, nil, Options{KDF: kdf}); err != nil {
+			t.Fatal(err)
+		} else if _, err = AES.Encrypt(text, password, nil, Options{KDF: kdf}); err == nil {
+			t.Fatalf("%s: expected an error for parameters over the limit", kdf.ID())
+		}
+	}
+}
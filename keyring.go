@@ -0,0 +1,159 @@
+package crypt
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+)
+
+// Header written by Keyring.Encrypt in front of the ciphertext of the key.
+// The header is associated data of AEAD keys and covered by the tag of
+// Options.MAC, so a changed key ID fails authentication.
+//
+//	0  4  "CKEY"
+//	4  1  version
+//	5  1  key ID length
+//	6  n  key ID
+const (
+	keyringMagic      = "CKEY"
+	keyringVersion    = 2
+	keyringHeaderSize = 6
+)
+
+// Keyring holds keys by ID. Encrypt uses the primary key and records its ID
+// in the ciphertext, Decrypt picks the key by that ID, so data encrypted with
+// an older key stays readable after the primary key is rotated. The ID is only
+// authenticated with AEAD keys and Options.MAC. A Keyring is safe for
+// concurrent use.
+type Keyring struct {
+	mu      sync.RWMutex
+	keys    map[string]*Crypt
+	primary string
+}
+
+func NewKeyring() *Keyring {
+	return &Keyring{keys: make(map[string]*Crypt)}
+}
+
+// Add adds the key c, created by NewAES, NewChaCha20Poly1305 and so on, under
+// id. The first key added becomes the primary key.
+func (k *Keyring) Add(id string, c *Crypt) error {
+	if len(id) == 0 || len(id) > 0xff {
+		return fmt.Errorf("crypt Keyring: key ID must be 1 to 255 bytes")
+	}
+	if c == nil {
+		return fmt.Errorf("crypt Keyring: nil Crypt for key %q", id)
+	}
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if _, ok := k.keys[id]; ok {
+		return fmt.Errorf("crypt Keyring: duplicate key %q", id)
+	}
+	k.keys[id] = c
+	if k.primary == "" {
+		k.primary = id
+	}
+	return nil
+}
+
+// SetPrimary makes id the key used by Encrypt.
+func (k *Keyring) SetPrimary(id string) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if _, ok := k.keys[id]; !ok {
+		return fmt.Errorf("crypt Keyring: unknown key %q", id)
+	}
+	k.primary = id
+	return nil
+}
+
+// Primary returns the ID of the primary key.
+func (k *Keyring) Primary() string {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	return k.primary
+}
+
+// Remove removes the key id, which must not be the primary key. Ciphertexts
+// of the key can no longer be decrypted.
+func (k *Keyring) Remove(id string) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if id == k.primary {
+		return fmt.Errorf("crypt Keyring: cannot remove the primary key %q", id)
+	}
+	delete(k.keys, id)
+	return nil
+}
+
+func (k *Keyring) Encrypt(src []byte) ([]byte, error) {
+	k.mu.RLock()
+	var id, c = k.primary, k.keys[k.primary]
+	k.mu.RUnlock()
+	if c == nil {
+		return nil, fmt.Errorf("crypt Keyring.Encrypt: no primary key")
+	}
+	var header = make([]byte, keyringHeaderSize, keyringHeaderSize+len(id))
+	copy(header, keyringMagic)
+	header[4] = keyringVersion
+	header[5] = byte(len(id))
+	header = append(header, id...)
+	var ciphertext []byte
+	var err error
+	if c.mac != MAC_NONE {
+		ciphertext, err = c.encryptThenMAC(src, header)
+	} else {
+		ciphertext, err = c.encrypt(src, keyringAAD(c, header))
+	}
+	if err != nil {
+		return nil, err
+	}
+	return append(header, ciphertext...), nil
+}
+
+func (k *Keyring) Decrypt(src []byte) ([]byte, error) {
+	id, n, err := parseKeyID(src)
+	if err != nil {
+		return nil, err
+	}
+	k.mu.RLock()
+	var c = k.keys[id]
+	k.mu.RUnlock()
+	if c == nil {
+		return nil, fmt.Errorf("crypt Keyring.Decrypt: unknown key %q", id)
+	}
+	if c.mac != MAC_NONE {
+		return c.verifyThenDecrypt(src[n:], src[:n])
+	}
+	return c.decrypt(src[n:], keyringAAD(c, src[:n]))
+}
+
+// keyringAAD returns the header followed by Options.AAD for AEAD keys, and
+// nil for the others, which cannot authenticate it.
+func keyringAAD(c *Crypt, header []byte) []byte {
+	if !c.authenticated() {
+		return nil
+	}
+	return append(append([]byte{}, header...), c.aad...)
+}
+
+// KeyID returns the ID of the key src was encrypted with, e.g. to find data
+// still encrypted with an old key.
+func KeyID(src []byte) (string, error) {
+	id, _, err := parseKeyID(src)
+	return id, err
+}
+
+func parseKeyID(src []byte) (id string, n int, err error) {
+	if len(src) < keyringHeaderSize || !bytes.Equal(src[:4], []byte(keyringMagic)) {
+		return "", 0, fmt.Errorf("crypt Keyring: missing key ID header")
+	}
+	if src[4] != keyringVersion {
+		return "", 0, fmt.Errorf("crypt Keyring: unsupported version %d", src[4])
+	}
+	n = keyringHeaderSize + int(src[5])
+	if len(src) < n {
+		return "", 0, fmt.Errorf("crypt Keyring: truncated key ID")
+	}
+	return string(src[keyringHeaderSize:n]), n, nil
+}
@@ -0,0 +1,108 @@
+package crypt
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestKeyring(t *testing.T) {
+	var text = []byte("Pack my box with five dozen liquor jugs")
+	var k = NewKeyring()
+	if _, err := k.Encrypt(text); err == nil {
+		t.Fatal("expected error without keys")
+	}
+	v1, err := NewAES(randBytes(32), nil, Options{Mode: MODE_CBC})
+	if err != nil {
+		t.Fatal(err)
+	}
+	v2, err := NewChaCha20Poly1305(randBytes(32), nil, Options{AAD: []byte("aad")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = k.Add("orders-v1", v1); err != nil {
+		t.Fatal(err)
+	}
+	if err = k.Add("orders-v1", v2); err == nil {
+		t.Fatal("expected duplicate key error")
+	}
+	old, err := k.Encrypt(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// rotate
+	if err = k.Add("orders-v2", v2); err != nil {
+		t.Fatal(err)
+	}
+	if k.Primary() != "orders-v1" {
+		t.Fatal("primary changed by Add")
+	}
+	if err = k.SetPrimary("orders-v2"); err != nil {
+		t.Fatal(err)
+	}
+	current, err := k.Encrypt(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for want, ciphertext := range map[string][]byte{"orders-v1": old, "orders-v2": current} {
+		if id, err := KeyID(ciphertext); err != nil || id != want {
+			t.Fatalf("KeyID = %q, %v, want %q", id, err, want)
+		}
+		plaintext, err := k.Decrypt(ciphertext)
+		if err != nil {
+			t.Fatal(want, err)
+		}
+		if !bytes.Equal(plaintext, text) {
+			t.Fatalf("%s: wrong plaintext %q", want, plaintext)
+		}
+	}
+
+	// the key ID is authenticated by AEAD keys: relabel the ChaCha20-Poly1305
+	// ciphertext with another ID of the same key
+	if err = k.Add("orders-v3", v2); err != nil {
+		t.Fatal(err)
+	}
+	var relabeled = append([]byte("CKEY\x02\x09orders-v3"), current[6+len("orders-v2"):]...)
+	if _, err = k.Decrypt(relabeled); !errors.Is(err, ErrAuthentication) {
+		t.Fatalf("relabeled ciphertext: %v", err)
+	}
+	// and by the tag of Options.MAC
+	etm, err := NewAES(randBytes(32), nil, Options{Mode: MODE_CBC, MAC: MAC_HMAC_SHA256})
+	if err != nil {
+		t.Fatal(err)
+	}
+	k.Add("etm-a", etm)
+	k.Add("etm-b", etm)
+	k.SetPrimary("etm-a")
+	ciphertext, err := k.Encrypt(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plaintext, err := k.Decrypt(ciphertext); err != nil || !bytes.Equal(plaintext, text) {
+		t.Fatalf("Options.MAC: %q, %v", plaintext, err)
+	}
+	ciphertext[len("CKEY\x02\x05etm-")] = 'b'
+	if _, err = k.Decrypt(ciphertext); !errors.Is(err, ErrAuthentication) {
+		t.Fatalf("relabeled Options.MAC ciphertext: %v", err)
+	}
+	// only version 2 is read
+	ciphertext[4] = 1
+	if _, err = k.Decrypt(ciphertext); err == nil {
+		t.Fatal("expected an error for version 1")
+	}
+	k.SetPrimary("orders-v2")
+
+	if err = k.Remove("orders-v2"); err == nil {
+		t.Fatal("expected error removing the primary key")
+	}
+	if err = k.Remove("orders-v1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = k.Decrypt(old); err == nil {
+		t.Fatal("expected unknown key error")
+	}
+	if _, err = k.Decrypt(text); err == nil {
+		t.Fatal("expected missing header error")
+	}
+}
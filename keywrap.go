@@ -0,0 +1,96 @@
+package crypt
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"fmt"
+
+	ciphers "github.com/kayon/crypt/cipher"
+)
+
+// KeyWrapper encrypts data keys under a master key, see EnvelopeCrypt.
+type KeyWrapper interface {
+	WrapKey(key []byte) ([]byte, error)
+	// UnwrapKey returns an error wrapping ErrAuthentication if wrapped was not
+	// produced by WrapKey with the same master key.
+	UnwrapKey(wrapped []byte) ([]byte, error)
+}
+
+// NewAESKeyWrap returns the AES Key Wrap of RFC 3394. It only wraps keys of at
+// least 16 bytes that are a multiple of 8 bytes.
+func NewAESKeyWrap(kek []byte) (KeyWrapper, error) {
+	block, err := aes.NewCipher(kek)
+	if err != nil {
+		return nil, err
+	}
+	return aesKeyWrap{block: block}, nil
+}
+
+// NewAESKeyWrapPad returns the AES Key Wrap with Padding of RFC 5649, which
+// wraps keys of any length.
+func NewAESKeyWrapPad(kek []byte) (KeyWrapper, error) {
+	block, err := aes.NewCipher(kek)
+	if err != nil {
+		return nil, err
+	}
+	return aesKeyWrap{block: block, pad: true}, nil
+}
+
+// NewAESGCMKeyWrap returns a KeyWrapper sealing keys with AES-GCM under a
+// random nonce, written in front of the sealed key.
+func NewAESGCMKeyWrap(kek []byte) (KeyWrapper, error) {
+	block, err := aes.NewCipher(kek)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return aesGCMKeyWrap{gcm: gcm}, nil
+}
+
+type aesKeyWrap struct {
+	block cipher.Block
+	pad   bool
+}
+
+func (w aesKeyWrap) WrapKey(key []byte) ([]byte, error) {
+	if w.pad {
+		return ciphers.KeyWrapPad(w.block, key)
+	}
+	return ciphers.KeyWrap(w.block, key)
+}
+
+func (w aesKeyWrap) UnwrapKey(wrapped []byte) (key []byte, err error) {
+	if w.pad {
+		key, err = ciphers.KeyUnwrapPad(w.block, wrapped)
+	} else {
+		key, err = ciphers.KeyUnwrap(w.block, wrapped)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("crypt UnwrapKey: %w", ErrAuthentication)
+	}
+	return key, nil
+}
+
+type aesGCMKeyWrap struct {
+	gcm cipher.AEAD
+}
+
+func (w aesGCMKeyWrap) WrapKey(key []byte) ([]byte, error) {
+	var nonce = randBytes(w.gcm.NonceSize())
+	return w.gcm.Seal(nonce, nonce, key, nil), nil
+}
+
+func (w aesGCMKeyWrap) UnwrapKey(wrapped []byte) ([]byte, error) {
+	var size = w.gcm.NonceSize()
+	if len(wrapped) < size+w.gcm.Overhead() {
+		return nil, fmt.Errorf("crypt UnwrapKey: %w", ErrAuthentication)
+	}
+	key, err := w.gcm.Open(nil, wrapped[:size], wrapped[size:], nil)
+	if err != nil {
+		return nil, fmt.Errorf("crypt UnwrapKey: %w", ErrAuthentication)
+	}
+	return key, nil
+}
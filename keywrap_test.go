@@ -0,0 +1,70 @@
+package crypt
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"testing"
+)
+
+func TestKeyWrap(t *testing.T) {
+	var vectors = []struct {
+		pad               bool
+		kek, key, wrapped string
+	}{
+		// RFC 3394 4.1 and 4.6
+		{false, "000102030405060708090a0b0c0d0e0f", "00112233445566778899aabbccddeeff", "1fa68b0a8112b447aef34bd8fb5a7b829d3e862371d2cfe5"},
+		{false, "000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f", "00112233445566778899aabbccddeeff000102030405060708090a0b0c0d0e0f", "28c9f404c4b810f4cbccb35cfb87f8263f5786e2d80ed326cbc7f0e71a99f43bfb988b9b7a02dd21"},
+		// RFC 5649 6
+		{true, "5840df6e29b02af1ab493b705bf16ea1ae8338f4dcc176a8", "c37b7e6492584340bed12207808941155068f738", "138bdeaa9b8fa7fc61f97742e72248ee5ae6ae5360d1ae6a5f54f373fa543b6a"},
+		{true, "5840df6e29b02af1ab493b705bf16ea1ae8338f4dcc176a8", "466f7250617369", "afbeb0f07dfbf5419200f2ccb50bb24f"},
+	}
+	for i, v := range vectors {
+		kek, _ := hex.DecodeString(v.kek)
+		key, _ := hex.DecodeString(v.key)
+		var w KeyWrapper
+		var err error
+		if v.pad {
+			w, err = NewAESKeyWrapPad(kek)
+		} else {
+			w, err = NewAESKeyWrap(kek)
+		}
+		if err != nil {
+			t.Fatal(i, err)
+		}
+		wrapped, err := w.WrapKey(key)
+		if err != nil {
+			t.Fatal(i, err)
+		}
+		if hex.EncodeToString(wrapped) != v.wrapped {
+			t.Fatalf("%d: wrapped %x", i, wrapped)
+		}
+		unwrapped, err := w.UnwrapKey(wrapped)
+		if err != nil {
+			t.Fatal(i, err)
+		}
+		if !bytes.Equal(unwrapped, key) {
+			t.Fatalf("%d: unwrapped %x", i, unwrapped)
+		}
+		wrapped[len(wrapped)-1] ^= 1
+		if _, err = w.UnwrapKey(wrapped); !errors.Is(err, ErrAuthentication) {
+			t.Fatalf("%d: expected authentication error, got %v", i, err)
+		}
+	}
+
+	w, err := NewAESGCMKeyWrap(randBytes(32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := randBytes(32)
+	wrapped, err := w.WrapKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if unwrapped, err := w.UnwrapKey(wrapped); err != nil || !bytes.Equal(unwrapped, key) {
+		t.Fatal("AES-GCM unwrap failed", err)
+	}
+	if _, err = w.UnwrapKey(wrapped[:10]); !errors.Is(err, ErrAuthentication) {
+		t.Fatal("expected authentication error", err)
+	}
+}
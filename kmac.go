@@ -0,0 +1,114 @@
+package crypt
+
+import (
+	"fmt"
+	"hash"
+
+	"golang.org/x/crypto/sha3"
+)
+
+const (
+	kmac128Rate = 168
+	kmac256Rate = 136
+)
+
+var KMAC cryptKMAC
+
+type cryptKMAC struct{}
+
+// Sum128 returns the KMAC128 of data, size bytes long, with the
+// customization string of NIST SP 800-185, which may be nil.
+func (cryptKMAC) Sum128(data, key, customization []byte, size int) ([]byte, error) {
+	m, err := KMAC.New128(key, customization, size)
+	if err != nil {
+		return nil, err
+	}
+	m.Write(data)
+	return m.Sum(nil), nil
+}
+
+// Sum256 returns the KMAC256 of data, size bytes long, with the
+// customization string of NIST SP 800-185, which may be nil.
+func (cryptKMAC) Sum256(data, key, customization []byte, size int) ([]byte, error) {
+	m, err := KMAC.New256(key, customization, size)
+	if err != nil {
+		return nil, err
+	}
+	m.Write(data)
+	return m.Sum(nil), nil
+}
+
+// New128 returns a streaming KMAC128 of size bytes, 0 or more.
+func (cryptKMAC) New128(key, customization []byte, size int) (hash.Hash, error) {
+	if size < 0 {
+		return nil, fmt.Errorf("crypt KMAC: invalid size %d", size)
+	}
+	return newKMAC(sha3.NewCShake128([]byte("KMAC"), customization), kmac128Rate, key, size), nil
+}
+
+// New256 returns a streaming KMAC256 of size bytes, 0 or more.
+func (cryptKMAC) New256(key, customization []byte, size int) (hash.Hash, error) {
+	if size < 0 {
+		return nil, fmt.Errorf("crypt KMAC: invalid size %d", size)
+	}
+	return newKMAC(sha3.NewCShake256([]byte("KMAC"), customization), kmac256Rate, key, size), nil
+}
+
+// kmac is cSHAKE with the padded key absorbed first and the output length
+// appended before the output is read.
+type kmac struct {
+	sha3.ShakeHash
+	// keyed is the state after the key, for Reset
+	keyed sha3.ShakeHash
+	rate  int
+	size  int
+}
+
+func newKMAC(h sha3.ShakeHash, rate int, key []byte, size int) *kmac {
+	// bytepad(encode_string(K), rate)
+	var padded = append(leftEncode(uint64(rate)), leftEncode(uint64(len(key))*8)...)
+	padded = append(padded, key...)
+	padded = append(padded, make([]byte, (rate-len(padded)%rate)%rate)...)
+	h.Write(padded)
+	return &kmac{ShakeHash: h.Clone(), keyed: h, rate: rate, size: size}
+}
+
+func (k *kmac) Size() int { return k.size }
+
+func (k *kmac) BlockSize() int { return k.rate }
+
+func (k *kmac) Reset() {
+	k.ShakeHash = k.keyed.Clone()
+}
+
+func (k *kmac) Sum(b []byte) []byte {
+	var h = k.ShakeHash.Clone()
+	h.Write(rightEncode(uint64(k.size) * 8))
+	var out = make([]byte, k.size)
+	h.Read(out)
+	return append(b, out...)
+}
+
+// leftEncode is left_encode of NIST SP 800-185, x as the fewest big endian
+// bytes preceded by their number.
+func leftEncode(x uint64) []byte {
+	var b = encodeUint(x)
+	return append([]byte{byte(len(b))}, b...)
+}
+
+// rightEncode is right_encode of NIST SP 800-185, the number of bytes follows.
+func rightEncode(x uint64) []byte {
+	var b = encodeUint(x)
+	return append(b, byte(len(b)))
+}
+
+func encodeUint(x uint64) []byte {
+	var b []byte
+	for ; x > 0; x >>= 8 {
+		b = append([]byte{byte(x)}, b...)
+	}
+	if len(b) == 0 {
+		b = []byte{0}
+	}
+	return b
+}
@@ -0,0 +1,153 @@
+package crypt
+
+import (
+	"crypto/cipher"
+	"fmt"
+	"hash"
+	"sort"
+
+	ciphers "github.com/kayon/crypt/cipher"
+)
+
+var CMAC cryptCMAC
+
+type cryptCMAC struct{}
+
+// Sum returns the CMAC (RFC 4493, NIST SP 800-38B) of data with the block
+// cipher method, such as METHOD_AES or METHOD_DES3. The cipher must have a 64
+// or 128 bit block.
+func (cryptCMAC) Sum(method CipherMethod, data, key []byte) ([]byte, error) {
+	m, err := CMAC.New(method, key)
+	if err != nil {
+		return nil, err
+	}
+	m.Write(data)
+	return m.Sum(nil), nil
+}
+
+// New returns a streaming CMAC with the block cipher method.
+func (cryptCMAC) New(method CipherMethod, key []byte) (hash.Hash, error) {
+	block, err := macBlock(method, key)
+	if err != nil {
+		return nil, err
+	}
+	return newCMAC(method, block)
+}
+
+var GMAC cryptGMAC
+
+type cryptGMAC struct{}
+
+// Sum returns the AES-GMAC (NIST SP 800-38D) of data, the tag of AES-GCM with
+// data as additional data and no plaintext. A key and nonce must only be used
+// once.
+func (cryptGMAC) Sum(data, key, nonce []byte) ([]byte, error) {
+	m, err := GMAC.New(key, nonce)
+	if err != nil {
+		return nil, err
+	}
+	m.Write(data)
+	return m.Sum(nil), nil
+}
+
+// New returns a streaming AES-GMAC.
+func (cryptGMAC) New(key, nonce []byte) (hash.Hash, error) {
+	block, err := macBlock(METHOD_AES, key)
+	if err != nil {
+		return nil, err
+	}
+	return newGMAC(METHOD_AES, block, nonce)
+}
+
+var Poly1305 cryptPoly1305
+
+type cryptPoly1305 struct{}
+
+// Sum returns the Poly1305 (RFC 8439) tag of data. The 32 byte key is a one
+// time key, it must never authenticate a second message.
+func (cryptPoly1305) Sum(data, key []byte) ([]byte, error) {
+	m, err := Poly1305.New(key)
+	if err != nil {
+		return nil, err
+	}
+	m.Write(data)
+	return m.Sum(nil), nil
+}
+
+// New returns a streaming Poly1305 with a 32 byte one time key. Sum does not
+// change the state, more may be written after it.
+func (cryptPoly1305) New(key []byte) (hash.Hash, error) {
+	if len(key) != 32 {
+		return nil, &KeySizeError{Name: "Poly1305", Got: len(key), Allowed: []int{32}}
+	}
+	return ciphers.NewPoly1305(key)
+}
+
+// NewCMAC returns a streaming CMAC keyed with the block cipher c already has,
+// for protocols that MAC with the cipher key, such as EMV and DESFire session
+// keys. c must have been created with a key rather than a password.
+func (c Crypt) NewCMAC() (hash.Hash, error) {
+	block, err := c.macBlock()
+	if err != nil {
+		return nil, err
+	}
+	return newCMAC(c.method, block)
+}
+
+// NewGMAC returns a streaming GMAC keyed with the block cipher c already has,
+// which must have a 128 bit block. See NewCMAC.
+func (c Crypt) NewGMAC(nonce []byte) (hash.Hash, error) {
+	block, err := c.macBlock()
+	if err != nil {
+		return nil, err
+	}
+	return newGMAC(c.method, block, nonce)
+}
+
+func (c Crypt) macBlock() (cipher.Block, error) {
+	if c.block == nil || c.mode == MODE_XTS {
+		return nil, fmt.Errorf("crypt %s: MAC requires a block cipher", c.method)
+	}
+	// without an IV the key is a password, the cipher key is derived from it
+	if c.salted() {
+		return nil, fmt.Errorf("crypt %s: MAC requires a key, not a password", c.method)
+	}
+	return c.block, nil
+}
+
+// macBlock returns the block cipher of method. Crypt cuts long keys down to
+// the largest size, a MAC key must be one of the sizes.
+func macBlock(method CipherMethod, key []byte) (cipher.Block, error) {
+	var b = blockCipherOf(method)
+	if b == nil {
+		return nil, fmt.Errorf("crypt %s: not a block cipher", method)
+	}
+	if k, err := verifyKey(method, MODE_CBC, key); err != nil {
+		return nil, err
+	} else if len(k) != len(key) {
+		var allowed = append([]int{}, b.keySizes...)
+		sort.Ints(allowed)
+		if method == METHOD_BLOWFISH {
+			allowed = []int{1, allowed[0]}
+		}
+		return nil, &KeySizeError{Method: method, Got: len(key), Allowed: allowed}
+	}
+	return b.newCipher(key)
+}
+
+func newCMAC(method CipherMethod, block cipher.Block) (hash.Hash, error) {
+	m, err := ciphers.NewCMAC(block)
+	if err != nil {
+		return nil, fmt.Errorf("crypt %s: CMAC requires a 64 or 128 bit block", method)
+	}
+	return m, nil
+}
+
+func newGMAC(method CipherMethod, block cipher.Block, nonce []byte) (hash.Hash, error) {
+	if block.BlockSize() != 16 {
+		return nil, fmt.Errorf("crypt %s: GMAC requires a 128 bit block", method)
+	} else if len(nonce) == 0 {
+		return nil, fmt.Errorf("crypt %s: GMAC requires a nonce: %w", method, ErrInvalidNonce)
+	}
+	return ciphers.NewGMAC(block, nonce)
+}
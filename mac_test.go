@@ -0,0 +1,151 @@
+package crypt
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/hex"
+	"errors"
+	"testing"
+
+	"golang.org/x/crypto/poly1305"
+)
+
+func TestCMACSum(t *testing.T) {
+	// RFC 4493 examples 2 and 3, NIST SP 800-38B TDEA example with Mlen 160
+	var vectors = []struct {
+		method         CipherMethod
+		key, data, mac string
+	}{
+		{METHOD_AES, "2b7e151628aed2a6abf7158809cf4f3c", "6bc1bee22e409f96e93d7e117393172a", "070a16b46b4d4144f79bdd9dd04a287c"},
+		{METHOD_AES, "2b7e151628aed2a6abf7158809cf4f3c", "6bc1bee22e409f96e93d7e117393172aae2d8a571e03ac9c9eb76fac45af8e5130c81c46a35ce411", "dfa66747de9ae63030ca32611497c827"},
+		{METHOD_DES3, "8aa83bf8cbda10620bc1bf19fbb6cd58bc313d4a371ca8b5", "6bc1bee22e409f96e93d7e117393172aae2d8a57", "743ddbe0ce2dc2ed"},
+	}
+	for i, v := range vectors {
+		key, _ := hex.DecodeString(v.key)
+		data, _ := hex.DecodeString(v.data)
+		mac, err := CMAC.Sum(v.method, data, key)
+		if err != nil {
+			t.Fatal(i, err)
+		} else if hex.EncodeToString(mac) != v.mac {
+			t.Fatalf("%d: %x", i, mac)
+		}
+
+		// the block cipher of a Crypt created with the key
+		c, err := New(v.method, key, nil, Options{Mode: MODE_ECB})
+		if err != nil {
+			t.Fatal(i, err)
+		}
+		m, err := c.NewCMAC()
+		if err != nil {
+			t.Fatal(i, err)
+		}
+		m.Write(data[:3])
+		m.Write(data[3:])
+		if !Verify(m.Sum(nil), mac) {
+			t.Fatalf("%d: Crypt.NewCMAC differs", i)
+		}
+	}
+
+	var keySizeErr *KeySizeError
+	if _, err := CMAC.Sum(METHOD_AES, nil, make([]byte, 40)); !errors.As(err, &keySizeErr) {
+		t.Fatal("expected a KeySizeError", err)
+	}
+	if _, err := CMAC.Sum(METHOD_CHACHA20, nil, make([]byte, 32)); err == nil {
+		t.Fatal("expected an error for a stream cipher")
+	}
+	c, _ := NewAES(testPassword, nil)
+	if _, err := c.NewCMAC(); err == nil {
+		t.Fatal("expected an error for a password")
+	}
+}
+
+func TestGMAC(t *testing.T) {
+	// OpenSSL 3.0 GMAC
+	var vectors = []struct {
+		key, nonce, data, mac string
+	}{
+		{"2b7e151628aed2a6abf7158809cf4f3c", "cafebabefacedbaddecaf888", "6bc1bee22e409f96e93d7e117393172aae2d8a571e03ac9c9eb76fac45af8e5130c81c46a35ce411", "a7b2adf15e13a8801747a09bd150edd6"},
+		{"000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f", "00112233445566778899aabbccddeeff01", "6bc1bee22e409f96e93d7e117393172aae2d8a57", "1fd081799256ef43b3a21102fcf878dd"},
+	}
+	for i, v := range vectors {
+		key, _ := hex.DecodeString(v.key)
+		nonce, _ := hex.DecodeString(v.nonce)
+		data, _ := hex.DecodeString(v.data)
+		mac, err := GMAC.Sum(data, key, nonce)
+		if err != nil {
+			t.Fatal(i, err)
+		} else if hex.EncodeToString(mac) != v.mac {
+			t.Fatalf("%d: %x", i, mac)
+		}
+	}
+
+	// the same as the tag of AES-GCM without plaintext, written in pieces
+	var key, nonce, data = randBytes(16), randBytes(12), randBytes(1000)
+	block, _ := aes.NewCipher(key)
+	gcm, _ := cipher.NewGCM(block)
+	c, _ := NewAES(key, randBytes(16), Options{Mode: MODE_CTR})
+	m, err := c.NewGMAC(nonce)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, n := range []int{0, 1, 15, 16, 17, 100} {
+		m.Reset()
+		m.Write(data[:n])
+		m.Write(data[n:])
+		if !Verify(m.Sum(nil), gcm.Seal(nil, nonce, nil, data)) {
+			t.Fatalf("split at %d: differs from AES-GCM", n)
+		}
+	}
+	if _, err = GMAC.Sum(data, key, nil); !errors.Is(err, ErrInvalidNonce) {
+		t.Fatal("expected ErrInvalidNonce", err)
+	}
+	c, _ = NewDES3(make([]byte, 24), make([]byte, 8))
+	if _, err = c.NewGMAC(nonce); err == nil {
+		t.Fatal("expected an error for a 64 bit block")
+	}
+}
+
+func TestPoly1305(t *testing.T) {
+	// RFC 8439 2.5.2
+	var key, _ = hex.DecodeString("85d6be7857556d337f4452fe42d506a80103808afb0db2fd4abff6af4149f51b")
+	var data = []byte("Cryptographic Forum Research Group")
+	mac, err := Poly1305.Sum(data, key)
+	if err != nil {
+		t.Fatal(err)
+	} else if hex.EncodeToString(mac) != "a8061dc1305136c6c22b8baf0c0127a9" {
+		t.Fatalf("%x", mac)
+	}
+	// Sum does not change the state
+	m, _ := Poly1305.New(key)
+	m.Write(data[:5])
+	m.Sum(nil)
+	m.Write(data[5:])
+	if !Verify(m.Sum(nil), mac) {
+		t.Fatal("New differs")
+	}
+	m.Reset()
+	m.Write(data)
+	if !Verify(m.Sum(nil), mac) {
+		t.Fatal("Reset differs")
+	}
+	var keySizeErr *KeySizeError
+	if _, err = Poly1305.Sum(data, key[:16]); !errors.As(err, &keySizeErr) || keySizeErr.Name != "Poly1305" {
+		t.Fatal("expected a KeySizeError for a short key", err)
+	}
+
+	// against golang.org/x/crypto/poly1305, with the largest limbs
+	var oneTimeKey [32]byte
+	for i, k := range [][]byte{randBytes(32), bytes.Repeat([]byte{0xff}, 32)} {
+		copy(oneTimeKey[:], k)
+		for _, size := range []int{0, 1, 15, 16, 17, 63, 64, 1000} {
+			for _, msg := range [][]byte{randBytes(size), bytes.Repeat([]byte{0xff}, size)} {
+				var want [16]byte
+				poly1305.Sum(&want, msg, &oneTimeKey)
+				if got, _ := Poly1305.Sum(msg, k); !bytes.Equal(got, want[:]) {
+					t.Fatalf("key %d, %d bytes: %x, want %x", i, size, got, want)
+				}
+			}
+		}
+	}
+}
@@ -2,7 +2,9 @@ package crypt
 
 import (
 	"crypto/md5"
+	"encoding/base64"
 	"encoding/hex"
+	"hash"
 )
 
 var MD5 cryptMD5
@@ -17,3 +19,12 @@ func (cryptMD5) Sum(plaintext []byte) []byte {
 func (cryptMD5) Hex(plaintext []byte) string {
 	return hex.EncodeToString(MD5.Sum(plaintext))
 }
+
+func (cryptMD5) Base64(plaintext []byte) string {
+	return base64.StdEncoding.EncodeToString(MD5.Sum(plaintext))
+}
+
+// New returns a streaming MD5.
+func (cryptMD5) New() hash.Hash {
+	return md5.New()
+}
@@ -9,6 +9,29 @@ const (
 	MODE_OFB
 	MODE_GCM
 	MODE_ECB
+	// MODE_CCM is Counter with CBC-MAC, NIST SP 800-38C and RFC 3610.
+	MODE_CCM
+	// MODE_EAX is EAX of Bellare, Rogaway and Wagner.
+	MODE_EAX
+	// MODE_OCB is OCB3, RFC 7253.
+	MODE_OCB
+	// MODE_GCMSIV is the nonce-misuse resistant AES-GCM-SIV of RFC 8452.
+	MODE_GCMSIV
+	// MODE_SIV is the deterministic AES-SIV of RFC 5297, with a key of 32, 48
+	// or 64 bytes and an optional nonce.
+	MODE_SIV
+	// MODE_XTS is XTS-AES of IEEE 1619 for storage, with a double length key
+	// and the IV as the tweak, see Crypt.EncryptSector.
+	MODE_XTS
+	// MODE_CBC_CTS is CBC with ciphertext stealing instead of padding, the
+	// ciphertext is as long as the plaintext, at least one block. See
+	// Options.CTS.
+	MODE_CBC_CTS
+	// MODE_IGE is the Infinite Garble Extension of Telegram MTProto, with an
+	// IV of two blocks.
+	MODE_IGE
+	// MODE_PCBC is the Propagating CBC of Kerberos v4.
+	MODE_PCBC
 )
 
 func (mode BlockMode) Not(modes ...BlockMode) bool {
@@ -24,6 +47,17 @@ func (mode BlockMode) Has(modes ...BlockMode) bool {
 	return !mode.Not(modes...)
 }
 
+// padded reports whether mode only encrypts full blocks, so the plaintext is
+// padded.
+func (mode BlockMode) padded() bool {
+	return mode.Has(MODE_CBC, MODE_ECB, MODE_IGE, MODE_PCBC)
+}
+
+// aead reports whether mode is an authenticated mode.
+func (mode BlockMode) aead() bool {
+	return mode.Has(MODE_GCM, MODE_CCM, MODE_EAX, MODE_OCB, MODE_GCMSIV)
+}
+
 func (mode BlockMode) String() string {
 	switch mode {
 	case MODE_CBC:
@@ -38,6 +72,24 @@ func (mode BlockMode) String() string {
 		return "GCM"
 	case MODE_ECB:
 		return "ECB"
+	case MODE_CCM:
+		return "CCM"
+	case MODE_EAX:
+		return "EAX"
+	case MODE_OCB:
+		return "OCB"
+	case MODE_GCMSIV:
+		return "GCM-SIV"
+	case MODE_SIV:
+		return "SIV"
+	case MODE_XTS:
+		return "XTS"
+	case MODE_CBC_CTS:
+		return "CBC-CTS"
+	case MODE_IGE:
+		return "IGE"
+	case MODE_PCBC:
+		return "PCBC"
 	}
 	return ""
 }
@@ -0,0 +1,310 @@
+package crypt
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/des"
+	_ "crypto/md5"
+	"crypto/rc4"
+	_ "crypto/sha1"
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"strings"
+
+	"github.com/Yawning/chacha20"
+	"golang.org/x/crypto/blowfish"
+	"golang.org/x/crypto/cast5"
+	"golang.org/x/crypto/pbkdf2"
+
+	ciphers "github.com/kayon/crypt/cipher"
+)
+
+const (
+	opensslSaltedText       = "Salted__"
+	opensslDefaultIter      = 10000
+	opensslBase64LineLength = 64
+)
+
+// OpenSSLOptions are the `openssl enc` flags that change the output.
+type OpenSSLOptions struct {
+	// Digest is -md, default crypto.SHA256 as in OpenSSL 1.1.0 and later.
+	Digest crypto.Hash
+	// PBKDF2 is -pbkdf2. Without it the key and IV come from EVP_BytesToKey.
+	PBKDF2 bool
+	// Iter is -iter and implies PBKDF2, default 10000.
+	Iter int
+	// Base64 is -a, base64 wrapped at 64 columns.
+	Base64 bool
+	// Salt is -S, 8 bytes, random if nil. Only use it for reproducible output.
+	Salt []byte
+}
+
+// OpenSSL reads and writes the format of `openssl enc` with a password. Cipher
+// names are the ones OpenSSL uses, e.g. aes-256-cbc, des-ede3-cbc, bf-cbc,
+// camellia-256-cbc, cast5-cbc, sm4-ctr, chacha20 or rc4.
+var OpenSSL cryptOpenSSL
+
+type cryptOpenSSL struct{}
+
+// Encrypt is `openssl enc -e -<cipherName> -pass pass:<password>`.
+func (cryptOpenSSL) Encrypt(plaintext, password []byte, cipherName string, args ...OpenSSLOptions) ([]byte, error) {
+	c, opts, err := openSSLSetup(cipherName, args)
+	if err != nil {
+		return nil, err
+	}
+	var salt = opts.Salt
+	if salt == nil {
+		salt = randBytes(saltTextByteSize)
+	} else if len(salt) != saltTextByteSize {
+		return nil, fmt.Errorf("crypt OpenSSL.Encrypt: salt must be %d bytes", saltTextByteSize)
+	}
+	key, iv := opts.deriveKeyIV(password, salt, c)
+	ciphertext, err := c.crypt(plaintext, key, iv, true)
+	if err != nil {
+		return nil, err
+	}
+	ciphertext = append(append([]byte(opensslSaltedText), salt...), ciphertext...)
+	if opts.Base64 {
+		ciphertext = opensslBase64Encode(ciphertext)
+	}
+	return ciphertext, nil
+}
+
+// Decrypt is `openssl enc -d -<cipherName> -pass pass:<password>`.
+func (cryptOpenSSL) Decrypt(ciphertext, password []byte, cipherName string, args ...OpenSSLOptions) ([]byte, error) {
+	c, opts, err := openSSLSetup(cipherName, args)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Base64 {
+		if ciphertext, err = opensslBase64Decode(ciphertext); err != nil {
+			return nil, err
+		}
+	}
+	salt, ok := getSalt(ciphertext)
+	if !ok {
+		return nil, fmt.Errorf("crypt OpenSSL.Decrypt: missing %s header", opensslSaltedText)
+	}
+	key, iv := opts.deriveKeyIV(password, salt[:], c)
+	return c.crypt(ciphertext[16:], key, iv, false)
+}
+
+func openSSLSetup(cipherName string, args []OpenSSLOptions) (c opensslCipher, opts OpenSSLOptions, err error) {
+	var ok bool
+	var name = strings.ToLower(cipherName)
+	if alias, found := opensslAliases[name]; found {
+		name = alias
+	}
+	if c, ok = opensslCiphers[name]; !ok {
+		return c, opts, fmt.Errorf("crypt OpenSSL: unsupported cipher %q", cipherName)
+	}
+	if len(args) > 0 {
+		opts = args[0]
+	}
+	if opts.Digest == 0 {
+		opts.Digest = crypto.SHA256
+	}
+	if !opts.Digest.Available() {
+		return c, opts, fmt.Errorf("crypt OpenSSL: unsupported digest %s", opts.Digest)
+	}
+	if opts.Iter != 0 {
+		opts.PBKDF2 = true
+	} else if opts.PBKDF2 {
+		opts.Iter = opensslDefaultIter
+	}
+	if opts.Iter < 0 {
+		return c, opts, fmt.Errorf("crypt OpenSSL: invalid iter %d", opts.Iter)
+	}
+	return c, opts, nil
+}
+
+func (opts OpenSSLOptions) deriveKeyIV(password, salt []byte, c opensslCipher) (key, iv []byte) {
+	var b []byte
+	var size = c.keySize + c.ivSize
+	if opts.PBKDF2 {
+		b = pbkdf2.Key(password, salt, opts.Iter, size, opts.Digest.New)
+	} else {
+		b = evpBytesToKey(opts.Digest, password, salt, size)
+	}
+	return b[:c.keySize], b[c.keySize:size]
+}
+
+// evpBytesToKey is OpenSSL's EVP_BytesToKey with one iteration.
+func evpBytesToKey(hash crypto.Hash, password, salt []byte, size int) []byte {
+	var data = append(append([]byte{}, password...), salt...)
+	var b []byte
+	var out = make([]byte, 0, size+hash.Size())
+	for len(out) < size {
+		h := hash.New()
+		h.Write(b)
+		h.Write(data)
+		b = h.Sum(nil)
+		out = append(out, b...)
+	}
+	return out[:size]
+}
+
+type opensslCipher struct {
+	keySize int
+	ivSize  int
+	mode    BlockMode
+	// block is nil for the stream ciphers
+	block  func(key []byte) (cipher.Block, error)
+	stream func(key, iv []byte) (cipher.Stream, error)
+}
+
+func (c opensslCipher) crypt(src, key, iv []byte, encrypt bool) (dst []byte, err error) {
+	if c.block == nil {
+		var stream cipher.Stream
+		if stream, err = c.stream(key, iv); err != nil {
+			return nil, err
+		}
+		dst = make([]byte, len(src))
+		stream.XORKeyStream(dst, src)
+		return dst, nil
+	}
+	var block cipher.Block
+	if block, err = c.block(key); err != nil {
+		return nil, err
+	}
+	var bs = block.BlockSize()
+	if c.mode.Has(MODE_CBC, MODE_ECB) {
+		if encrypt {
+			if src, err = Padding(PAD_PKCS7, src, bs); err != nil {
+				return nil, err
+			}
+		} else if len(src) == 0 || len(src)%bs != 0 {
+			return nil, ErrInvalidPadding
+		}
+	}
+	dst = make([]byte, len(src))
+	switch c.mode {
+	case MODE_CBC:
+		if encrypt {
+			cipher.NewCBCEncrypter(block, iv).CryptBlocks(dst, src)
+		} else {
+			cipher.NewCBCDecrypter(block, iv).CryptBlocks(dst, src)
+		}
+	case MODE_ECB:
+		if encrypt {
+			ciphers.NewECBEncrypter(block).CryptBlocks(dst, src)
+		} else {
+			ciphers.NewECBDecrypter(block).CryptBlocks(dst, src)
+		}
+	case MODE_CFB:
+		if encrypt {
+			cipher.NewCFBEncrypter(block, iv).XORKeyStream(dst, src)
+		} else {
+			cipher.NewCFBDecrypter(block, iv).XORKeyStream(dst, src)
+		}
+	case MODE_OFB:
+		cipher.NewOFB(block, iv).XORKeyStream(dst, src)
+	case MODE_CTR:
+		cipher.NewCTR(block, iv).XORKeyStream(dst, src)
+	}
+	if !encrypt && c.mode.Has(MODE_CBC, MODE_ECB) {
+		return UnPadding(PAD_PKCS7, dst, bs)
+	}
+	return dst, nil
+}
+
+var opensslAliases = map[string]string{
+	"aes128":      "aes-128-cbc",
+	"aes192":      "aes-192-cbc",
+	"aes256":      "aes-256-cbc",
+	"des":         "des-cbc",
+	"des3":        "des-ede3-cbc",
+	"des-ede3":    "des-ede3-ecb",
+	"bf":          "bf-cbc",
+	"blowfish":    "bf-cbc",
+	"camellia128": "camellia-128-cbc",
+	"camellia192": "camellia-192-cbc",
+	"camellia256": "camellia-256-cbc",
+	"cast":        "cast5-cbc",
+	"cast-cbc":    "cast5-cbc",
+	"sm4":         "sm4-cbc",
+}
+
+var opensslCiphers = func() map[string]opensslCipher {
+	var table = map[string]opensslCipher{
+		"chacha20": {keySize: chacha20.KeySize, ivSize: 16, stream: opensslChaCha20},
+		"rc4":      {keySize: 16, stream: opensslRC4},
+	}
+	var modes = map[string]BlockMode{"cbc": MODE_CBC, "ecb": MODE_ECB, "cfb": MODE_CFB, "ofb": MODE_OFB}
+	var add = func(prefix string, keySize, blockSize int, block func([]byte) (cipher.Block, error), ctr bool) {
+		for name, mode := range modes {
+			c := opensslCipher{keySize: keySize, ivSize: blockSize, mode: mode, block: block}
+			if mode == MODE_ECB {
+				c.ivSize = 0
+			}
+			table[prefix+"-"+name] = c
+		}
+		if ctr {
+			table[prefix+"-ctr"] = opensslCipher{keySize: keySize, ivSize: blockSize, mode: MODE_CTR, block: block}
+		}
+	}
+	for _, size := range []int{16, 24, 32} {
+		add(fmt.Sprintf("aes-%d", size*8), size, aes.BlockSize, aes.NewCipher, true)
+	}
+	add("des", 8, des.BlockSize, des.NewCipher, false)
+	add("des-ede3", 24, des.BlockSize, des.NewTripleDESCipher, false)
+	add("bf", 16, blowfish.BlockSize, func(key []byte) (cipher.Block, error) { return blowfish.NewCipher(key) }, false)
+	for _, size := range []int{16, 24, 32} {
+		add(fmt.Sprintf("camellia-%d", size*8), size, 16, ciphers.NewCamellia, true)
+	}
+	add("cast5", 16, cast5.BlockSize, newCAST5, false)
+	add("sm4", 16, 16, ciphers.NewSM4, true)
+	return table
+}()
+
+// opensslChaCha20 uses the 16 byte IV of OpenSSL, a little endian block
+// counter followed by the 12 byte nonce.
+func opensslChaCha20(key, iv []byte) (cipher.Stream, error) {
+	stream, err := chacha20.NewCipher(key, iv[4:])
+	if err != nil {
+		return nil, err
+	}
+	if err = stream.Seek(uint64(binary.LittleEndian.Uint32(iv))); err != nil {
+		return nil, err
+	}
+	return stream, nil
+}
+
+func opensslRC4(key, _ []byte) (cipher.Stream, error) {
+	return rc4.NewCipher(key)
+}
+
+func opensslBase64Encode(src []byte) []byte {
+	var encoded = base64.StdEncoding.EncodeToString(src)
+	var b bytes.Buffer
+	for len(encoded) > opensslBase64LineLength {
+		b.WriteString(encoded[:opensslBase64LineLength])
+		b.WriteByte('\n')
+		encoded = encoded[opensslBase64LineLength:]
+	}
+	if len(encoded) > 0 {
+		b.WriteString(encoded)
+		b.WriteByte('\n')
+	}
+	return b.Bytes()
+}
+
+func opensslBase64Decode(src []byte) ([]byte, error) {
+	var stripped = bytes.Map(func(r rune) rune {
+		if r == '\n' || r == '\r' || r == ' ' || r == '\t' {
+			return -1
+		}
+		return r
+	}, src)
+	var dst = make([]byte, base64.StdEncoding.DecodedLen(len(stripped)))
+	n, err := base64.StdEncoding.Decode(dst, stripped)
+	if err != nil {
+		return nil, fmt.Errorf("crypt OpenSSL.Decrypt: %w", err)
+	}
+	return dst[:n], nil
+}
@@ -0,0 +1,89 @@
+package crypt
+
+import (
+	"bytes"
+	"crypto"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// testdata/openssl holds ciphertexts of plaintext.txt made with OpenSSL 3.0:
+//
+//	openssl enc -<cipher> [-md <digest>] [-pbkdf2 [-iter <n>]] [-a] \
+//		-pass pass:crypt-openssl-password -in plaintext.txt
+//
+// named <cipher>.[<digest>.][pbkdf2[-<n>].]{enc,b64}. The legacy provider is
+// needed for des, bf, cast5 and rc4.
+func TestOpenSSL(t *testing.T) {
+	var password = []byte("crypt-openssl-password")
+	var digests = map[string]crypto.Hash{"md5": crypto.MD5, "sha1": crypto.SHA1, "sha256": crypto.SHA256, "sha512": crypto.SHA512}
+	plaintext, err := os.ReadFile("testdata/openssl/plaintext.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	files, err := filepath.Glob("testdata/openssl/*.*.*")
+	if err != nil || len(files) == 0 {
+		t.Fatal("no vectors", err)
+	}
+	for _, file := range files {
+		name := filepath.Base(file)
+		parts := strings.Split(name, ".")
+		cipherName := parts[0]
+		var opts OpenSSLOptions
+		for _, part := range parts[1 : len(parts)-1] {
+			if hash, ok := digests[part]; ok {
+				opts.Digest = hash
+			} else if part == "pbkdf2" {
+				opts.PBKDF2 = true
+			} else if iter, ok := strings.CutPrefix(part, "pbkdf2-"); ok {
+				if opts.Iter, err = strconv.Atoi(iter); err != nil {
+					t.Fatal(name, err)
+				}
+			} else {
+				t.Fatalf("%s: unknown part %q", name, part)
+			}
+		}
+		opts.Base64 = parts[len(parts)-1] == "b64"
+
+		golden, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		decrypted, err := OpenSSL.Decrypt(golden, password, cipherName, opts)
+		if err != nil {
+			t.Fatal(name, err)
+		}
+		if !bytes.Equal(decrypted, plaintext) {
+			t.Fatalf("%s: decrypt wrong %q", name, decrypted)
+		}
+
+		raw := golden
+		if opts.Base64 {
+			if raw, err = opensslBase64Decode(golden); err != nil {
+				t.Fatal(name, err)
+			}
+		}
+		opts.Salt = raw[8:16]
+		encrypted, err := OpenSSL.Encrypt(plaintext, password, cipherName, opts)
+		if err != nil {
+			t.Fatal(name, err)
+		}
+		if !bytes.Equal(encrypted, golden) {
+			t.Fatalf("%s: encrypt differs from OpenSSL", name)
+		}
+	}
+
+	if _, err = OpenSSL.Decrypt([]byte("Salted__12345678"), password, "aes-256-gcm"); err == nil {
+		t.Fatal("expected unsupported cipher")
+	}
+	ciphertext, err := OpenSSL.Encrypt(plaintext, password, "AES256")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decrypted, err := OpenSSL.Decrypt(ciphertext, []byte("wrong"), "aes256"); err == nil && bytes.Equal(decrypted, plaintext) {
+		t.Fatal("decrypted with a wrong password")
+	}
+}
@@ -2,6 +2,7 @@ package crypt
 
 import (
 	"bytes"
+	"crypto/subtle"
 	"fmt"
 )
 
@@ -51,18 +52,18 @@ func PKCS7Padding(plaintext []byte, blockSize int) ([]byte, error) {
 
 func PKCS7UnPadding(ciphertext []byte, blockSize int) ([]byte, error) {
 	length := len(ciphertext)
-	if length%blockSize != 0 {
-		return nil, fmt.Errorf("crypt.PKCS7UnPadding ciphertext's length isn't a multiple of blockSize")
+	if length == 0 || blockSize < 1 || blockSize > 255 || length%blockSize != 0 {
+		return nil, ErrInvalidPadding
 	}
+	// constant time over the last block
 	unpadding := int(ciphertext[length-1])
-	if unpadding > blockSize || unpadding <= 0 {
-		return nil, fmt.Errorf("crypt.PKCS7UnPadding invalid padding found: %v", unpadding)
+	good := subtle.ConstantTimeLessOrEq(1, unpadding) & subtle.ConstantTimeLessOrEq(unpadding, blockSize)
+	for i := 1; i <= blockSize; i++ {
+		inPad := subtle.ConstantTimeLessOrEq(i, unpadding)
+		good &= subtle.ConstantTimeSelect(inPad, subtle.ConstantTimeByteEq(ciphertext[length-i], byte(unpadding)), 1)
 	}
-	var pad = ciphertext[length-unpadding : length-1]
-	for _, v := range pad {
-		if int(v) != unpadding {
-			return nil, fmt.Errorf("crypt.PKCS7UnPadding invalid padding found")
-		}
+	if good != 1 {
+		return nil, ErrInvalidPadding
 	}
 	return ciphertext[:length-unpadding], nil
 }
@@ -91,6 +92,9 @@ func ISO97971UnPadding(ciphertext []byte, blockSize int) ([]byte, error) {
 	if err != nil {
 		return nil, err
 	}
+	if len(data) == 0 || data[len(data)-1] != 0x80 || len(ciphertext)-len(data) > blockSize {
+		return nil, ErrInvalidPadding
+	}
 	return data[:len(data)-1], nil
 }
 
@@ -106,20 +110,18 @@ func AnsiX923Padding(plaintext []byte, blockSize int) ([]byte, error) {
 
 func AnsiX923UnPadding(ciphertext []byte, blockSize int) ([]byte, error) {
 	length := len(ciphertext)
-	if length%blockSize != 0 {
-		return nil, fmt.Errorf("crypt.AnsiX923UnPadding ciphertext's length isn't a multiple of blockSize")
+	if length == 0 || blockSize < 1 || blockSize > 255 || length%blockSize != 0 {
+		return nil, ErrInvalidPadding
 	}
+	// constant time over the last block
 	unpadding := int(ciphertext[length-1])
-	if unpadding > blockSize || unpadding < 1 {
-		return nil, fmt.Errorf("crypt.AnsiX923UnPadding invalid padding found: %d", unpadding)
-	}
-	if length-unpadding < length-2 {
-		pad := ciphertext[length-unpadding : length-2]
-		for _, v := range pad {
-			if int(v) != 0 {
-				return nil, fmt.Errorf("crypt.AnsiX923UnPadding invalid padding found")
-			}
-		}
+	good := subtle.ConstantTimeLessOrEq(1, unpadding) & subtle.ConstantTimeLessOrEq(unpadding, blockSize)
+	for i := 2; i <= blockSize; i++ {
+		inPad := subtle.ConstantTimeLessOrEq(i, unpadding)
+		good &= subtle.ConstantTimeSelect(inPad, subtle.ConstantTimeByteEq(ciphertext[length-i], 0), 1)
+	}
+	if good != 1 {
+		return nil, ErrInvalidPadding
 	}
 	return ciphertext[0 : length-unpadding], nil
 }
@@ -136,12 +138,12 @@ func ISO10126Padding(plaintext []byte, blockSize int) ([]byte, error) {
 
 func ISO10126UnPadding(ciphertext []byte, blockSize int) ([]byte, error) {
 	length := len(ciphertext)
-	if length%blockSize != 0 {
-		return nil, fmt.Errorf("crypt.ISO10126UnPadding ciphertext's length isn't a multiple of blockSize")
+	if length == 0 || blockSize < 1 || length%blockSize != 0 {
+		return nil, ErrInvalidPadding
 	}
 	unpadding := int(ciphertext[length-1])
 	if unpadding > blockSize || unpadding < 1 {
-		return nil, fmt.Errorf("crypt.ISO10126UnPadding invalid padding found: %v", unpadding)
+		return nil, ErrInvalidPadding
 	}
 	return ciphertext[:length-unpadding], nil
 }
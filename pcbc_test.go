@@ -0,0 +1,35 @@
+package crypt
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+)
+
+func TestPCBC(t *testing.T) {
+	// OpenSSL destest.c pcbc_ok, DES_pcbc_encrypt of cbc_data zero padded to
+	// 32 bytes
+	var key, _ = hex.DecodeString("0123456789abcdef")
+	var iv, _ = hex.DecodeString("fedcba9876543210")
+	var padded = []byte("7654321 Now is the time for \x00\x00\x00\x00")
+	var text = padded[:29]
+	ciphertext, err := DES.Encrypt(padded, key, iv, Options{Mode: MODE_PCBC, Padding: PAD_NOPADDING})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hex.EncodeToString(ciphertext) != "ccd173ffab2039f46decb470a0e56b15aea6bf61ed7d9c9ff717463b8ab3cc88" {
+		t.Fatalf("%x", ciphertext)
+	}
+	if plaintext, err := DES.Decrypt(ciphertext, key, iv, Options{Mode: MODE_PCBC, Padding: PAD_NOPADDING}); err != nil || !bytes.Equal(plaintext, padded) {
+		t.Fatalf("decrypt %q, %v", plaintext, err)
+	}
+
+	// a change propagates to every following block, unlike CBC
+	ciphertext[3] ^= 1
+	plaintext, _ := DES.Decrypt(ciphertext[:24], key, iv, Options{Mode: MODE_PCBC, Padding: PAD_NOPADDING})
+	if bytes.Equal(plaintext[16:24], padded[16:24]) {
+		t.Fatal("error did not propagate")
+	}
+
+	testModeStream(t, MODE_PCBC, text)
+}
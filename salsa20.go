@@ -0,0 +1,134 @@
+package crypt
+
+import (
+	"crypto/cipher"
+	"crypto/subtle"
+	"encoding/binary"
+
+	"golang.org/x/crypto/salsa20/salsa"
+)
+
+const (
+	salsa20KeyByteSize    = 32
+	salsa20NonceByteSize  = 8
+	xsalsa20NonceByteSize = 24
+)
+
+var Salsa20 cryptSalsa20
+
+type cryptSalsa20 struct{}
+
+func (cryptSalsa20) Encrypt(plaintext, key, nonce []byte, args ...Options) ([]byte, error) {
+	c, err := NewSalsa20(key, nonce, args...)
+	if err != nil {
+		return nil, err
+	}
+	return c.Encrypt(plaintext)
+}
+
+func (cryptSalsa20) Decrypt(ciphertext, key, nonce []byte, args ...Options) ([]byte, error) {
+	c, err := NewSalsa20(key, nonce, args...)
+	if err != nil {
+		return nil, err
+	}
+	return c.Decrypt(ciphertext)
+}
+
+var XSalsa20 cryptXSalsa20
+
+type cryptXSalsa20 struct{}
+
+func (cryptXSalsa20) Encrypt(plaintext, key, nonce []byte, args ...Options) ([]byte, error) {
+	c, err := NewXSalsa20(key, nonce, args...)
+	if err != nil {
+		return nil, err
+	}
+	return c.Encrypt(plaintext)
+}
+
+func (cryptXSalsa20) Decrypt(ciphertext, key, nonce []byte, args ...Options) ([]byte, error) {
+	c, err := NewXSalsa20(key, nonce, args...)
+	if err != nil {
+		return nil, err
+	}
+	return c.Decrypt(ciphertext)
+}
+
+func salsa20NonceSize(method CipherMethod) int {
+	if method == METHOD_XSALSA20 {
+		return xsalsa20NonceByteSize
+	}
+	return salsa20NonceByteSize
+}
+
+func salsa20Encrypt(method CipherMethod, src, key, password, nonce []byte, kdf KDF) ([]byte, error) {
+	return streamEncrypt(src, key, password, nonce, kdf, salsa20NonceSize(method), salsa20KeyByteSize, salsa20StreamOf(method))
+}
+
+func salsa20Decrypt(method CipherMethod, src, key, password, nonce []byte) ([]byte, error) {
+	return streamDecrypt(src, key, password, nonce, salsa20NonceSize(method), salsa20KeyByteSize, salsa20StreamOf(method))
+}
+
+func salsa20StreamOf(method CipherMethod) func(key, nonce []byte) (cipher.Stream, error) {
+	return func(key, nonce []byte) (cipher.Stream, error) {
+		return newSalsa20Stream(method, key, nonce)
+	}
+}
+
+// salsa20Stream is Salsa20 as a cipher.Stream, with the 64 bit block counter
+// starting at 0 like libsodium crypto_stream_salsa20_xor. XSalsa20 derives the
+// key with HSalsa20 from the first 16 bytes of the nonce and uses the last 8.
+type salsa20Stream struct {
+	key     [32]byte
+	counter [16]byte
+	buf     [64]byte
+	// off is the offset of the unused keystream in buf
+	off int
+}
+
+func newSalsa20Stream(method CipherMethod, key, nonce []byte) (*salsa20Stream, error) {
+	if len(key) != salsa20KeyByteSize {
+		return nil, &KeySizeError{Method: method, Got: len(key), Allowed: []int{salsa20KeyByteSize}}
+	} else if len(nonce) != salsa20NonceSize(method) {
+		return nil, nonceSizeError(method, len(nonce), salsa20NonceSize(method))
+	}
+	var s = &salsa20Stream{off: len(salsa20Stream{}.buf)}
+	copy(s.key[:], key)
+	if method == METHOD_XSALSA20 {
+		var in [16]byte
+		copy(in[:], nonce[:16])
+		salsa.HSalsa20(&s.key, &in, &s.key, &salsa.Sigma)
+		nonce = nonce[16:]
+	}
+	copy(s.counter[:8], nonce)
+	return s, nil
+}
+
+func (s *salsa20Stream) XORKeyStream(dst, src []byte) {
+	if len(dst) < len(src) {
+		panic("crypt: output smaller than input")
+	}
+	if len(src) > 0 && s.off < len(s.buf) {
+		n := subtle.XORBytes(dst, src, s.buf[s.off:])
+		s.off += n
+		dst, src = dst[n:], src[n:]
+	}
+	if blocks := len(src) / len(s.buf); blocks > 0 {
+		var n = blocks * len(s.buf)
+		var counter = s.counter
+		salsa.XORKeyStream(dst[:n], src[:n], &counter, &s.key)
+		s.advance(uint64(blocks))
+		dst, src = dst[n:], src[n:]
+	}
+	if len(src) > 0 {
+		var counter = s.counter
+		s.buf = [64]byte{}
+		salsa.XORKeyStream(s.buf[:], s.buf[:], &counter, &s.key)
+		s.advance(1)
+		s.off = subtle.XORBytes(dst, src, s.buf[:])
+	}
+}
+
+func (s *salsa20Stream) advance(blocks uint64) {
+	binary.LittleEndian.PutUint64(s.counter[8:], binary.LittleEndian.Uint64(s.counter[8:])+blocks)
+}
@@ -0,0 +1,101 @@
+package crypt
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"testing"
+)
+
+// made with libsodium 1.0.18 crypto_stream_salsa20_xor, crypto_stream_xsalsa20_xor
+// and crypto_secretbox_easy
+var (
+	sodiumKey, _     = hex.DecodeString("000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f")
+	sodiumNonce8, _  = hex.DecodeString("6465666768696a6b")
+	sodiumNonce24, _ = hex.DecodeString("c8c9cacbcccdcecfd0d1d2d3d4d5d6d7d8d9dadbdcdddedf")
+	sodiumMessage    = []byte("The quick brown fox jumps over the lazy dog, then the lazy dog wakes up and chases the fox away.")
+	sodiumSalsa20, _ = hex.DecodeString("8eac7acff9c99ceffd450de37057e1509a0c9865a7b80040985692ca41cc494434ea4eab65a204f6cbeafa7b7302e7f920073f4b77db173b44ca500a6d1269cbe77b5cf51ca3a2494a1c692bf5152cb8b67e5eb5366fc9a4c036c9607ecd2a40")
+	sodiumXSalsa, _  = hex.DecodeString("bd254d8b35a2d97b35c2d3536b19f01a7c5453a0b3d334ae77082327637dbef592374f19214828e2e987e4ef8e49d76a8b33eafd669d49e01c5ebdfae6d40281fefdd038caad2bad594b88f6a1bb3ac2acebca92b7419bbb7e1d0ce93fdb9feb")
+	sodiumBox, _     = hex.DecodeString("97c636f98309deb1b3a960b4ab7b8cf6ae3a0a55314738a1e6c8e1b1c14ad12f837ce6b569c848f11507f2e8ecc10282f7f395278ba222ad5c4a8bfae2a733d4a7b89e8eba04d7bc6b1c0cec27ddc6b2d1b79a26ba5a363659f5716103d31fd90ede2cffb59ac3ae234df375a95ad3bd")
+)
+
+func TestSalsa20(t *testing.T) {
+	ciphertext, err := Salsa20.Encrypt(sodiumMessage, sodiumKey, sodiumNonce8)
+	if err != nil {
+		t.Fatal(err)
+	} else if !bytes.Equal(ciphertext, sodiumSalsa20) {
+		t.Fatalf("Salsa20 %x", ciphertext)
+	}
+	ciphertext, err = XSalsa20.Encrypt(sodiumMessage, sodiumKey, sodiumNonce24)
+	if err != nil {
+		t.Fatal(err)
+	} else if !bytes.Equal(ciphertext, sodiumXSalsa) {
+		t.Fatalf("XSalsa20 %x", ciphertext)
+	}
+	if plaintext, err := XSalsa20.Decrypt(ciphertext, sodiumKey, sodiumNonce24); err != nil || !bytes.Equal(plaintext, sodiumMessage) {
+		t.Fatal("XSalsa20 decrypt failed", err)
+	}
+
+	// the keystream continues across calls of any size
+	stream, err := newSalsa20Stream(METHOD_SALSA20, sodiumKey, sodiumNonce8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out = make([]byte, len(sodiumMessage))
+	for i, n := 0, 1; i < len(out); i, n = i+n, n+7 {
+		if i+n > len(out) {
+			n = len(out) - i
+		}
+		stream.XORKeyStream(out[i:i+n], sodiumMessage[i:i+n])
+	}
+	if !bytes.Equal(out, sodiumSalsa20) {
+		t.Fatalf("Salsa20 stream %x", out)
+	}
+
+	// password
+	for _, method := range []CipherMethod{METHOD_SALSA20, METHOD_XSALSA20} {
+		c, err := New(method, testPassword, nil)
+		if err != nil {
+			t.Fatal(method, err)
+		}
+		if ciphertext, err = c.Encrypt(sodiumMessage); err != nil {
+			t.Fatal(method, err)
+		}
+		if plaintext, err := c.Decrypt(ciphertext); err != nil || !bytes.Equal(plaintext, sodiumMessage) {
+			t.Fatal(method, "password decrypt failed", err)
+		}
+	}
+}
+
+func TestSecretBox(t *testing.T) {
+	box, err := SecretBox.Seal(sodiumMessage, sodiumKey, sodiumNonce24)
+	if err != nil {
+		t.Fatal(err)
+	} else if !bytes.Equal(box, sodiumBox) {
+		t.Fatalf("Seal %x", box)
+	}
+	if message, err := SecretBox.Open(sodiumBox, sodiumKey, sodiumNonce24); err != nil || !bytes.Equal(message, sodiumMessage) {
+		t.Fatal("Open failed", err)
+	}
+
+	// random nonce in front of the box
+	if box, err = SecretBox.Seal(sodiumMessage, sodiumKey, nil); err != nil {
+		t.Fatal(err)
+	}
+	if message, err := SecretBox.Open(box, sodiumKey, nil); err != nil || !bytes.Equal(message, sodiumMessage) {
+		t.Fatal("Open without nonce failed", err)
+	}
+
+	var tampered = append([]byte{}, sodiumBox...)
+	tampered[len(tampered)-1] ^= 1
+	if _, err = SecretBox.Open(tampered, sodiumKey, sodiumNonce24); !errors.Is(err, ErrAuthentication) {
+		t.Fatal("expected ErrAuthentication", err)
+	}
+	if _, err = SecretBox.Open(sodiumBox, sodiumKey, sodiumNonce8); !errors.Is(err, ErrInvalidNonce) {
+		t.Fatal("expected ErrInvalidNonce", err)
+	}
+	var keySizeErr *KeySizeError
+	if _, err = SecretBox.Seal(sodiumMessage, sodiumKey[:16], sodiumNonce24); !errors.As(err, &keySizeErr) {
+		t.Fatal("expected a KeySizeError", err)
+	}
+}
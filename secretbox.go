@@ -0,0 +1,63 @@
+package crypt
+
+import (
+	"fmt"
+
+	"golang.org/x/crypto/nacl/secretbox"
+)
+
+// SecretBox is NaCl and libsodium crypto_secretbox, XSalsa20-Poly1305 with a
+// 32 byte key and a 24 byte nonce. A box is the 16 byte tag followed by the
+// ciphertext, the same as crypto_secretbox_easy.
+var SecretBox cryptSecretBox
+
+type cryptSecretBox struct{}
+
+// Seal returns the box of message. If nonce is nil a random nonce is generated
+// and written in front of the box, Open reads it from there when it is given
+// no nonce.
+func (cryptSecretBox) Seal(message, key, nonce []byte) ([]byte, error) {
+	var out []byte
+	if nonce == nil {
+		nonce = randBytes(xsalsa20NonceByteSize)
+		out = append(out, nonce...)
+	}
+	k, n, err := secretBoxKeyNonce(key, nonce)
+	if err != nil {
+		return nil, err
+	}
+	return secretbox.Seal(out, message, n, k), nil
+}
+
+// Open returns the message of box. It returns an error wrapping
+// ErrAuthentication if box was not sealed with key and nonce.
+func (cryptSecretBox) Open(box, key, nonce []byte) ([]byte, error) {
+	if nonce == nil {
+		if len(box) < xsalsa20NonceByteSize {
+			return nil, fmt.Errorf("crypt SecretBox.Open: %w", ErrAuthentication)
+		}
+		nonce, box = box[:xsalsa20NonceByteSize], box[xsalsa20NonceByteSize:]
+	}
+	k, n, err := secretBoxKeyNonce(key, nonce)
+	if err != nil {
+		return nil, err
+	}
+	message, ok := secretbox.Open(nil, box, n, k)
+	if !ok {
+		return nil, fmt.Errorf("crypt SecretBox.Open: %w", ErrAuthentication)
+	}
+	return message, nil
+}
+
+func secretBoxKeyNonce(key, nonce []byte) (*[32]byte, *[24]byte, error) {
+	if len(key) != salsa20KeyByteSize {
+		return nil, nil, &KeySizeError{Method: METHOD_XSALSA20, Got: len(key), Allowed: []int{salsa20KeyByteSize}}
+	} else if len(nonce) != xsalsa20NonceByteSize {
+		return nil, nil, fmt.Errorf("crypt SecretBox: %w %d, must be %d", ErrInvalidNonce, len(nonce), xsalsa20NonceByteSize)
+	}
+	var k [32]byte
+	var n [24]byte
+	copy(k[:], key)
+	copy(n[:], nonce)
+	return &k, &n, nil
+}
@@ -0,0 +1,168 @@
+package crypt
+
+import (
+	"crypto/cipher"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/Yawning/chacha20"
+)
+
+// StreamSeeker is a keystream that can be moved to any byte offset of the
+// message, to encrypt or decrypt part of a large ciphertext. Seek takes
+// io.SeekStart or io.SeekCurrent, the end of the message is not known.
+type StreamSeeker interface {
+	cipher.Stream
+	io.Seeker
+}
+
+// NewStreamSeeker returns the keystream of a ChaCha20 Crypt, from the block
+// at Options.Counter, or of a block cipher in MODE_CTR, from the IV. It is at
+// offset 0. The Crypt must have an IV, without one the key and IV are only
+// known once the salted header is read.
+func (c Crypt) NewStreamSeeker() (StreamSeeker, error) {
+	if c.iv == nil {
+		return nil, fmt.Errorf("crypt %s.NewStreamSeeker: requires an IV", c.method)
+	}
+	return c.newStreamSeeker(c.key, c.iv)
+}
+
+func (c Crypt) newStreamSeeker(key, iv []byte) (StreamSeeker, error) {
+	var s = new(streamSeeker)
+	if c.method == METHOD_CHACHA20 {
+		stream, err := newChaCha20Stream(key, iv, c.counter)
+		if err != nil {
+			return nil, err
+		}
+		s.seekTo = (&chacha20Seeker{c: stream, counter: c.counter, ietf: len(iv) == chacha20.INonceSize}).seek
+	} else if blockCipherOf(c.method) != nil && c.mode == MODE_CTR {
+		block, err := newBlockCipher(c.method, key)
+		if err != nil {
+			return nil, err
+		} else if len(iv) != block.BlockSize() {
+			return nil, nonceSizeError(c.method, len(iv), block.BlockSize())
+		}
+		s.seekTo = (&ctrSeeker{block: block, iv: append([]byte{}, iv...)}).seek
+	} else {
+		return nil, fmt.Errorf("crypt %s: seeking requires ChaCha20 or MODE_CTR", c.method)
+	}
+	if _, err := s.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// streamSeeker keeps the position for io.Seeker, seekTo returns the keystream
+// at an offset from the start of the message.
+type streamSeeker struct {
+	cipher.Stream
+	seekTo func(offset int64) (cipher.Stream, error)
+	pos    int64
+}
+
+func (s *streamSeeker) XORKeyStream(dst, src []byte) {
+	s.Stream.XORKeyStream(dst, src)
+	s.pos += int64(len(src))
+}
+
+func (s *streamSeeker) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += s.pos
+	default:
+		return 0, fmt.Errorf("crypt: StreamSeeker does not support whence %d", whence)
+	}
+	if offset < 0 {
+		return 0, fmt.Errorf("crypt: negative offset %d", offset)
+	}
+	stream, err := s.seekTo(offset)
+	if err != nil {
+		return 0, err
+	}
+	s.Stream, s.pos = stream, offset
+	return offset, nil
+}
+
+type chacha20Seeker struct {
+	c       *chacha20.Cipher
+	counter uint64
+	ietf    bool
+}
+
+func (s *chacha20Seeker) seek(offset int64) (cipher.Stream, error) {
+	var block = s.counter + uint64(offset)/chacha20.BlockSize
+	if block < s.counter || s.ietf && block > math.MaxUint32 {
+		return nil, fmt.Errorf("crypt ChaCha20: offset %d is past the end of the keystream", offset)
+	}
+	if err := s.c.Seek(block); err != nil {
+		return nil, err
+	}
+	var skip = make([]byte, offset%chacha20.BlockSize)
+	s.c.KeyStream(skip)
+	return s.c, nil
+}
+
+// ctrSeeker adds the block offset to the IV the same way cipher.NewCTR
+// increments it, as one big endian number.
+type ctrSeeker struct {
+	block cipher.Block
+	iv    []byte
+}
+
+func (s *ctrSeeker) seek(offset int64) (cipher.Stream, error) {
+	var blockSize = int64(s.block.BlockSize())
+	var counter = append([]byte{}, s.iv...)
+	var carry = uint64(offset / blockSize)
+	for i := len(counter) - 1; i >= 0 && carry > 0; i-- {
+		carry += uint64(counter[i])
+		counter[i] = byte(carry)
+		carry >>= 8
+	}
+	var stream = cipher.NewCTR(s.block, counter)
+	var skip = make([]byte, offset%blockSize)
+	stream.XORKeyStream(skip, skip)
+	return stream, nil
+}
+
+// DecryptRange decrypts n bytes of the plaintext from offset off of a ChaCha20
+// or MODE_CTR ciphertext in r, reading only the header and those bytes. Without
+// an IV the key and IV are derived from the header r starts with, the same as
+// Decrypt. A range past the end of the ciphertext is cut short. Options.MAC
+// is not supported, the tag covers the whole ciphertext.
+func (c Crypt) DecryptRange(r io.ReaderAt, off, n int64) ([]byte, error) {
+	if off < 0 || n < 0 {
+		return nil, fmt.Errorf("crypt %s.DecryptRange: invalid range %d, %d", c.method, off, n)
+	} else if c.mac != MAC_NONE {
+		return nil, fmt.Errorf("crypt %s.DecryptRange: Options.MAC is not supported", c.method)
+	}
+	var key, iv = c.key, c.iv
+	var start int64
+	if c.salted() {
+		header, _, err := readHeader(io.NewSectionReader(r, 0, math.MaxInt64))
+		if err != nil {
+			return nil, err
+		} else if header == nil {
+			return nil, fmt.Errorf("crypt %s.DecryptRange: no salted header", c.method)
+		}
+		if _, key, iv, err = parseHeader(header, c.key, c.password, c.ivSize(), c.saltKeyByteSize()); err != nil {
+			return nil, err
+		}
+		start = int64(len(header))
+	}
+	stream, err := c.newStreamSeeker(key, iv)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = stream.Seek(off, io.SeekStart); err != nil {
+		return nil, err
+	}
+	// n may be far beyond the end, only what is there is read
+	buf, err := io.ReadAll(io.NewSectionReader(r, start+off, n))
+	if err != nil {
+		return nil, err
+	}
+	stream.XORKeyStream(buf, buf)
+	return buf, nil
+}
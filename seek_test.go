@@ -0,0 +1,201 @@
+package crypt
+
+import (
+	"bytes"
+	"encoding/hex"
+	"io"
+	"math"
+	"testing"
+)
+
+func TestChaCha20Counter(t *testing.T) {
+	// made with libsodium 1.0.18 crypto_stream_chacha20_ietf_xor_ic (RFC 8439
+	// 2.4.2), crypto_stream_chacha20_xor_ic and crypto_stream_xchacha20_xor_ic
+	var text = []byte("Ladies and Gentlemen of the class of '99: If I could offer you only one tip for the future, sunscreen would be it.")
+	var vectors = []struct {
+		nonce      string
+		counter    uint64
+		ciphertext string
+	}{
+		{"000000000000004a00000000", 1, "6e2e359a2568f98041ba0728dd0d6981e97e7aec1d4360c20a27afccfd9fae0bf91b65c5524733ab8f593dabcd62b3571639d624e65152ab8f530c359f0861d807ca0dbf500d6a6156a38e088a22b65e52bc514d16ccf806818ce91ab77937365af90bbf74a35be6b40b8eedf2785e42874d"},
+		{"6465666768696a6b", math.MaxUint32, "ef7707c08b5420922ebba2eb32094b34986f8e3a04fec65cdd5cf1c8ca4a34a36fae8c04a23c323b2244e2d0ca3c9767ccd30af11799bd552eb425f40f694381a623c3c98523593855c14e447e398c4532e66c655eb1229e418476f6dd351e022675ab88c3159b36c897229bd32f01932c2e"},
+		{"c8c9cacbcccdcecfd0d1d2d3d4d5d6d7d8d9dadbdcdddedf", 7, "a088d824db629986e40659a23bf5abdb647d2a66aae8eb682d66c9bc37d926adbf38d351a868a5941ab1d9189165b87f29d5d5079630f480f229fde4c7778721736b1f4c6fd00dac095fa9f4feda7d06f26fce17067a4c72037c4fb03e2341e47810ca53c550debfce096cbe9dd088ae72da"},
+	}
+	for _, v := range vectors {
+		nonce, _ := hex.DecodeString(v.nonce)
+		c, err := NewChaCha20(sodiumKey, nonce, Options{Counter: v.counter})
+		if err != nil {
+			t.Fatal(len(nonce), err)
+		}
+		ciphertext, err := c.Encrypt(text)
+		if err != nil {
+			t.Fatal(len(nonce), err)
+		}
+		if hex.EncodeToString(ciphertext) != v.ciphertext {
+			t.Fatalf("%d byte nonce: %x", len(nonce), ciphertext)
+		}
+		var buf bytes.Buffer
+		w, _ := c.NewEncryptWriter(&buf)
+		w.Write(text)
+		w.Close()
+		if !bytes.Equal(buf.Bytes(), ciphertext) {
+			t.Fatalf("%d byte nonce: NewEncryptWriter differs", len(nonce))
+		}
+		if plaintext, err := c.Decrypt(ciphertext); err != nil || !bytes.Equal(plaintext, text) {
+			t.Fatal(len(nonce), "decrypt failed", err)
+		}
+		// the shortcuts take the counter too
+		if shortcut, err := ChaCha20.Encrypt(text, sodiumKey, nonce, Options{Counter: v.counter}); err != nil || !bytes.Equal(shortcut, ciphertext) {
+			t.Fatalf("%d byte nonce: ChaCha20.Encrypt %x, %v", len(nonce), shortcut, err)
+		}
+		if plaintext, err := ChaCha20.Decrypt(ciphertext, sodiumKey, nonce, Options{Counter: v.counter}); err != nil || !bytes.Equal(plaintext, text) {
+			t.Fatalf("%d byte nonce: ChaCha20.Decrypt %q, %v", len(nonce), plaintext, err)
+		}
+	}
+
+	if _, err := NewChaCha20(sodiumKey, make([]byte, 12), Options{Counter: math.MaxUint32 + 1}); err == nil {
+		t.Fatal("expected an error for a counter over 32 bits")
+	}
+	if _, err := NewAES(sodiumKey, make([]byte, 16), Options{Mode: MODE_CTR, Counter: 1}); err == nil {
+		t.Fatal("expected an error for Options.Counter with AES")
+	}
+	c, _ := NewChaCha20(sodiumKey, make([]byte, 12), Options{Counter: 1})
+	if _, err := c.EncryptEnvelope(text); err == nil {
+		t.Fatal("expected an error for Options.Counter in an envelope")
+	}
+}
+
+func TestStreamSeeker(t *testing.T) {
+	var text = randBytes(1000)
+	var ivCarry, _ = hex.DecodeString("000102030405060708090a0bfffffffe")
+	var crypts = map[string]func() (*Crypt, error){
+		"ChaCha20":      func() (*Crypt, error) { return NewChaCha20(sodiumKey, randBytes(8)) },
+		"ChaCha20/IETF": func() (*Crypt, error) { return NewChaCha20(sodiumKey, randBytes(12), Options{Counter: 1}) },
+		"XChaCha20":     func() (*Crypt, error) { return NewChaCha20(sodiumKey, randBytes(24), Options{Counter: 3}) },
+		"AES/CTR":       func() (*Crypt, error) { return NewAES(sodiumKey, ivCarry, Options{Mode: MODE_CTR}) },
+		"Twofish/CTR":   func() (*Crypt, error) { return NewTwofish(sodiumKey, randBytes(16), Options{Mode: MODE_CTR}) },
+	}
+	for name, fn := range crypts {
+		c, err := fn()
+		if err != nil {
+			t.Fatal(name, err)
+		}
+		ciphertext, err := c.Encrypt(text)
+		if err != nil {
+			t.Fatal(name, err)
+		}
+		s, err := c.NewStreamSeeker()
+		if err != nil {
+			t.Fatal(name, err)
+		}
+		for _, r := range [][2]int64{{0, 1000}, {1, 2}, {15, 17}, {63, 129}, {64, 128}, {500, 999}, {999, 1000}, {3, 1000}} {
+			if _, err = s.Seek(r[0], io.SeekStart); err != nil {
+				t.Fatal(name, r, err)
+			}
+			var plaintext = make([]byte, r[1]-r[0])
+			s.XORKeyStream(plaintext, ciphertext[r[0]:r[1]])
+			if !bytes.Equal(plaintext, text[r[0]:r[1]]) {
+				t.Fatalf("%s: range %v differs", name, r)
+			}
+		}
+		// io.SeekCurrent from the end of the last range
+		if pos, err := s.Seek(-10, io.SeekCurrent); err != nil || pos != 990 {
+			t.Fatal(name, pos, err)
+		}
+		var plaintext = make([]byte, 10)
+		s.XORKeyStream(plaintext, ciphertext[990:])
+		if !bytes.Equal(plaintext, text[990:]) {
+			t.Fatalf("%s: io.SeekCurrent differs", name)
+		}
+		if _, err = s.Seek(-1, io.SeekStart); err == nil {
+			t.Fatal(name, "expected an error for a negative offset")
+		}
+		if _, err = s.Seek(0, io.SeekEnd); err == nil {
+			t.Fatal(name, "expected an error for io.SeekEnd")
+		}
+	}
+
+	s, _ := ChaCha20.NewStreamSeeker(sodiumKey, make([]byte, 12), Options{Counter: math.MaxUint32})
+	if _, err := s.Seek(63, io.SeekStart); err != nil {
+		t.Fatal(err)
+	} else if _, err = s.Seek(64, io.SeekStart); err == nil {
+		t.Fatal("expected an error past the 32 bit counter")
+	}
+	if _, err := AES.NewStreamSeeker(sodiumKey, ivCarry); err == nil {
+		t.Fatal("expected an error for MODE_CBC")
+	}
+	c, _ := NewChaCha20(sodiumKey, nil)
+	if _, err := c.NewStreamSeeker(); err == nil {
+		t.Fatal("expected an error without an IV")
+	}
+}
+
+// countingReaderAt counts the bytes read from it
+type countingReaderAt struct {
+	r    io.ReaderAt
+	read int
+}
+
+func (c *countingReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	n, err := c.r.ReadAt(p, off)
+	c.read += n
+	return n, err
+}
+
+func TestDecryptRange(t *testing.T) {
+	var text = randBytes(100000)
+	var crypts = map[string]func() (*Crypt, error){
+		"AES/CTR": func() (*Crypt, error) { return NewAES(testPassword, nil, Options{Mode: MODE_CTR}) },
+		"AES/CTR/KDF": func() (*Crypt, error) {
+			return NewAES(testPassword, nil, Options{Mode: MODE_CTR, KDF: Scrypt{N: 1 << 10}})
+		},
+		"AES/CTR/iv":   func() (*Crypt, error) { return NewAES(sodiumKey, randBytes(16), Options{Mode: MODE_CTR}) },
+		"ChaCha20":     func() (*Crypt, error) { return NewChaCha20(testPassword, nil) },
+		"ChaCha20/iv":  func() (*Crypt, error) { return NewChaCha20(sodiumKey, randBytes(12), Options{Counter: 1}) },
+		"Camellia/CTR": func() (*Crypt, error) { return NewCamellia(testPassword, nil, Options{Mode: MODE_CTR}) },
+	}
+	for name, fn := range crypts {
+		c, err := fn()
+		if err != nil {
+			t.Fatal(name, err)
+		}
+		ciphertext, err := c.Encrypt(text)
+		if err != nil {
+			t.Fatal(name, err)
+		}
+		var header = len(ciphertext) - len(text)
+		for _, r := range [][2]int64{{0, 100000}, {0, 1}, {65535, 4097}, {99999, 1}, {12345, 0}} {
+			var counting = &countingReaderAt{r: bytes.NewReader(ciphertext)}
+			plaintext, err := c.DecryptRange(counting, r[0], r[1])
+			if err != nil {
+				t.Fatal(name, r, err)
+			}
+			if !bytes.Equal(plaintext, text[r[0]:r[0]+r[1]]) {
+				t.Fatalf("%s: range %v differs", name, r)
+			}
+			// the header is read in 16 byte steps
+			if max := int(r[1]) + header + 16; counting.read > max {
+				t.Fatalf("%s: range %v read %d bytes", name, r, counting.read)
+			}
+		}
+		plaintext, err := c.DecryptRange(bytes.NewReader(ciphertext), 99990, 100)
+		if err != nil || !bytes.Equal(plaintext, text[99990:]) {
+			t.Fatal(name, "range past the end", err)
+		}
+		// the rest of the ciphertext
+		plaintext, err = c.DecryptRange(bytes.NewReader(ciphertext), 99000, 1<<62)
+		if err != nil || !bytes.Equal(plaintext, text[99000:]) {
+			t.Fatal(name, "huge range", err)
+		}
+	}
+
+	c, _ := NewAES(testPassword, nil)
+	ciphertext, _ := c.Encrypt(text)
+	if _, err := c.DecryptRange(bytes.NewReader(ciphertext), 0, 16); err == nil {
+		t.Fatal("expected an error for MODE_CBC")
+	}
+	c, _ = NewChaCha20(testPassword, nil, Options{MAC: MAC_HMAC_SHA256})
+	if _, err := c.DecryptRange(bytes.NewReader(ciphertext), 0, 16); err == nil {
+		t.Fatal("expected an error for Options.MAC")
+	}
+}
@@ -0,0 +1,236 @@
+package crypt
+
+import (
+	"crypto/cipher"
+	"fmt"
+	"io"
+
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+// Segmented AEAD stream, following the STREAM construction used by age.
+//
+// The plaintext is split into segments of segmentSize bytes, each sealed on its
+// own. The nonce of a segment is the base nonce with its last five bytes XORed
+// with a big-endian 32-bit segment counter and a last-segment flag, so removing,
+// reordering or truncating segments makes Open fail. Only the final segment may
+// be shorter than segmentSize, and it is empty only if the plaintext is empty.
+//
+// When no IV is given the stream starts with the same salted or versioned
+// header as Encrypt writes.
+const segmentSize = 64 * 1024
+
+// NewSealWriter returns a writer that encrypts everything written to it into
+// the segmented AEAD stream format and writes it to w. It is supported by AES
+// in the AEAD modes, the ChaCha20-Poly1305 methods and by ChaCha20, which seals
+// with ChaCha20-Poly1305 or, for 24 byte nonces, XChaCha20-Poly1305. Close must
+// be called to write the final segment, it does not close w.
+func (c Crypt) NewSealWriter(w io.Writer) (io.WriteCloser, error) {
+	if err := c.checkSegmented(); err != nil {
+		return nil, err
+	}
+	var key, iv = c.key, c.iv
+	if c.salted() {
+		var header []byte
+		var err error
+		if header, key, iv, err = genHeader(c.kdf, c.key, c.password, c.ivSize(), c.saltKeyByteSize()); err != nil {
+			return nil, err
+		}
+		if _, err = w.Write(header); err != nil {
+			return nil, err
+		}
+	}
+	aead, err := c.newSegmentAEAD(key, iv)
+	if err != nil {
+		return nil, err
+	}
+	return &segmentWriter{w: w, aead: aead, nonce: iv, aad: c.aad}, nil
+}
+
+// NewOpenReader returns a reader that decrypts and authenticates the segmented
+// AEAD stream read from r. Read returns an error as soon as a segment fails to
+// authenticate or the stream ends before its final segment. Every segment is
+// authenticated together with Options.AAD.
+func (c Crypt) NewOpenReader(r io.Reader) (io.Reader, error) {
+	if err := c.checkSegmented(); err != nil {
+		return nil, err
+	}
+	key, iv, _, r, err := c.readHeader(r)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := c.newSegmentAEAD(key, iv)
+	if err != nil {
+		return nil, err
+	}
+	return &segmentReader{r: r, aead: aead, nonce: iv, aad: c.aad}, nil
+}
+
+func (c Crypt) checkSegmented() error {
+	if c.counter != 0 {
+		return fmt.Errorf("crypt %s: Options.Counter is not supported by the segmented stream", c.method)
+	}
+	switch c.method {
+	case METHOD_AES:
+		if !c.mode.aead() {
+			return fmt.Errorf("crypt AES: segmented stream requires an AEAD mode")
+		}
+		// the segment counter takes the last five bytes of the nonce
+		if c.nonceSize < 8 || aeadMaxLength(c.mode, c.nonceSize) < segmentSize {
+			return fmt.Errorf("crypt AES: segmented stream does not support %s with %d byte nonces", c.mode, c.nonceSize)
+		}
+	case METHOD_CHACHA20, METHOD_CHACHA20POLY1305, METHOD_XCHACHA20POLY1305:
+	default:
+		return fmt.Errorf("crypt %s: segmented stream is not supported", c.method)
+	}
+	return nil
+}
+
+func (c Crypt) newSegmentAEAD(key, iv []byte) (cipher.AEAD, error) {
+	switch c.method {
+	case METHOD_AES:
+		if len(iv) != c.nonceSize {
+			return nil, nonceSizeError(c.method, len(iv), c.nonceSize)
+		}
+		block, err := newBlockCipher(c.method, key)
+		if err != nil {
+			return nil, err
+		}
+		return newAEAD(c.mode, key, block, c.nonceSize, c.tagSize)
+	case METHOD_CHACHA20:
+		switch len(iv) {
+		case chacha20poly1305.NonceSize:
+			return chacha20poly1305.New(key)
+		case chacha20poly1305.NonceSizeX:
+			return chacha20poly1305.NewX(key)
+		}
+		return nil, nonceSizeError(c.method, len(iv), chacha20poly1305.NonceSize, chacha20poly1305.NonceSizeX)
+	case METHOD_CHACHA20POLY1305, METHOD_XCHACHA20POLY1305:
+		if len(iv) != chacha20Poly1305NonceSize(c.method) {
+			return nil, nonceSizeError(c.method, len(iv), chacha20Poly1305NonceSize(c.method))
+		}
+		return newChaCha20Poly1305(c.method, key)
+	}
+	return nil, fmt.Errorf("crypt %s: segmented stream is not supported", c.method)
+}
+
+func segmentNonce(base []byte, counter uint32, last bool) []byte {
+	var nonce = append([]byte{}, base...)
+	var n = len(nonce)
+	nonce[n-5] ^= byte(counter >> 24)
+	nonce[n-4] ^= byte(counter >> 16)
+	nonce[n-3] ^= byte(counter >> 8)
+	nonce[n-2] ^= byte(counter)
+	if last {
+		nonce[n-1] ^= 1
+	}
+	return nonce
+}
+
+type segmentWriter struct {
+	w       io.Writer
+	aead    cipher.AEAD
+	nonce   []byte
+	aad     []byte
+	counter uint32
+	buf     []byte
+	closed  bool
+}
+
+func (sw *segmentWriter) Write(p []byte) (int, error) {
+	if sw.closed {
+		return 0, fmt.Errorf("crypt: write to closed writer")
+	}
+	sw.buf = append(sw.buf, p...)
+	// a full segment is only sealed once more data follows, the final one
+	// must carry the last flag
+	for len(sw.buf) > segmentSize {
+		if err := sw.seal(sw.buf[:segmentSize], false); err != nil {
+			return 0, err
+		}
+		sw.buf = append(sw.buf[:0], sw.buf[segmentSize:]...)
+	}
+	return len(p), nil
+}
+
+func (sw *segmentWriter) Close() error {
+	if sw.closed {
+		return nil
+	}
+	sw.closed = true
+	err := sw.seal(sw.buf, true)
+	sw.buf = nil
+	return err
+}
+
+func (sw *segmentWriter) seal(plaintext []byte, last bool) error {
+	if sw.counter == 1<<32-1 {
+		return fmt.Errorf("crypt: segmented stream too large")
+	}
+	ciphertext := sw.aead.Seal(nil, segmentNonce(sw.nonce, sw.counter, last), plaintext, sw.aad)
+	sw.counter++
+	_, err := sw.w.Write(ciphertext)
+	return err
+}
+
+type segmentReader struct {
+	r       io.Reader
+	aead    cipher.AEAD
+	nonce   []byte
+	aad     []byte
+	counter uint32
+	in      []byte
+	out     []byte
+	err     error
+}
+
+func (sr *segmentReader) Read(p []byte) (int, error) {
+	for len(sr.out) == 0 {
+		if sr.err != nil {
+			return 0, sr.err
+		}
+		sr.out, sr.err = sr.next()
+	}
+	n := copy(p, sr.out)
+	sr.out = sr.out[n:]
+	return n, nil
+}
+
+// next opens the next segment. One byte past a full segment is read ahead to
+// tell whether it is the final one.
+func (sr *segmentReader) next() ([]byte, error) {
+	var size = segmentSize + sr.aead.Overhead()
+	if sr.in == nil {
+		sr.in = make([]byte, 0, size+1)
+	}
+	var n = len(sr.in)
+	m, err := io.ReadFull(sr.r, sr.in[n:size+1])
+	sr.in = sr.in[:n+m]
+	var last bool
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		last = true
+	} else if err != nil {
+		return nil, err
+	}
+	var segment = sr.in
+	if !last {
+		segment = sr.in[:size]
+	}
+	if len(segment) < sr.aead.Overhead() {
+		return nil, fmt.Errorf("crypt: segmented stream truncated")
+	}
+	if sr.counter == 1<<32-1 {
+		return nil, fmt.Errorf("crypt: segmented stream too large")
+	}
+	plaintext, err := sr.aead.Open(nil, segmentNonce(sr.nonce, sr.counter, last), segment, sr.aad)
+	if err != nil {
+		return nil, fmt.Errorf("crypt: segment %d: %w", sr.counter, ErrAuthentication)
+	}
+	sr.counter++
+	if last {
+		sr.in = sr.in[:0]
+		return plaintext, io.EOF
+	}
+	sr.in = append(sr.in[:0], sr.in[size:]...)
+	return plaintext, nil
+}
@@ -0,0 +1,124 @@
+package crypt
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+func sealSegments(t *testing.T, c *Crypt, text []byte) []byte {
+	var buf bytes.Buffer
+	w, err := c.NewSealWriter(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = w.Write(text); err != nil {
+		t.Fatal(err)
+	}
+	if err = w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func openSegments(c *Crypt, ciphertext []byte) ([]byte, error) {
+	r, err := c.NewOpenReader(bytes.NewReader(ciphertext))
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(r)
+}
+
+func TestSegment(t *testing.T) {
+	var crypts = make(map[string]*Crypt)
+	var err error
+	if crypts["AES"], err = NewAES(randBytes(32), nil, Options{Mode: MODE_GCM}); err != nil {
+		t.Fatal(err)
+	}
+	if crypts["AES/iv"], err = NewAES(randBytes(32), randBytes(12), Options{Mode: MODE_GCM}); err != nil {
+		t.Fatal(err)
+	}
+	if crypts["AES/OCB"], err = NewAES(randBytes(32), nil, Options{Mode: MODE_OCB, TagSize: 12}); err != nil {
+		t.Fatal(err)
+	}
+	if crypts["ChaCha20"], err = NewChaCha20(randBytes(32), nil); err != nil {
+		t.Fatal(err)
+	}
+	if crypts["XChaCha20-Poly1305"], err = NewXChaCha20Poly1305(randBytes(32), nil); err != nil {
+		t.Fatal(err)
+	}
+	if crypts["ChaCha20/iv"], err = NewChaCha20(randBytes(32), randBytes(12)); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, c := range crypts {
+		for _, size := range []int{0, 1, segmentSize, segmentSize + 1, 3 * segmentSize} {
+			text := randBytes(size)
+			ciphertext := sealSegments(t, c, text)
+			plaintext, err := openSegments(c, ciphertext)
+			if err != nil {
+				t.Fatal(name, size, err)
+			}
+			if !bytes.Equal(plaintext, text) {
+				t.Fatalf("%s: segmented stream wrong, size %d", name, size)
+			}
+		}
+	}
+}
+
+func TestSegmentTampering(t *testing.T) {
+	c, err := NewChaCha20(randBytes(32), randBytes(24))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var text = randBytes(3 * segmentSize)
+	var ciphertext = sealSegments(t, c, text)
+	var sealed = segmentSize + 16
+
+	flipped := append([]byte{}, ciphertext...)
+	flipped[sealed+7] ^= 1
+	if _, err = openSegments(c, flipped); err == nil {
+		t.Fatal("segmented stream: modified segment not detected")
+	}
+
+	if _, err = openSegments(c, ciphertext[:2*sealed]); err == nil {
+		t.Fatal("segmented stream: truncation not detected")
+	}
+
+	swapped := append([]byte{}, ciphertext[sealed:2*sealed]...)
+	swapped = append(swapped, ciphertext[:sealed]...)
+	swapped = append(swapped, ciphertext[2*sealed:]...)
+	if _, err = openSegments(c, swapped); err == nil {
+		t.Fatal("segmented stream: reordering not detected")
+	}
+
+	if _, err = AES.NewSealWriter(io.Discard, randBytes(32), nil); err == nil {
+		t.Fatal("segmented stream: AES without MODE_GCM accepted")
+	}
+}
+
+func TestSegmentChaCha20AAD(t *testing.T) {
+	// Options.AAD of ChaCha20 is for the segmented stream, Encrypt ignores it
+	c, err := NewChaCha20(testPassword, nil, Options{AAD: []byte("aad"), MAC: MAC_HMAC_SHA256})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var text = randBytes(100)
+	ciphertext, err := c.Encrypt(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plaintext, err := c.Decrypt(ciphertext); err != nil || !bytes.Equal(plaintext, text) {
+		t.Fatal("decrypt failed", err)
+	}
+	if _, err = c.EncryptEnvelope(text); err != nil {
+		t.Fatal(err)
+	}
+
+	c, _ = NewChaCha20(testPassword, nil, Options{AAD: []byte("aad")})
+	ciphertext = sealSegments(t, c, text)
+	other, _ := NewChaCha20(testPassword, nil, Options{AAD: []byte("other")})
+	if _, err = openSegments(other, ciphertext); err == nil {
+		t.Fatal("segmented stream: wrong associated data not detected")
+	}
+}
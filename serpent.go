@@ -0,0 +1,23 @@
+package crypt
+
+const serpentSaltKeyByteSize = 32
+
+var Serpent cryptSerpent
+
+type cryptSerpent struct{}
+
+func (cryptSerpent) Encrypt(plaintext, key, iv []byte, args ...Options) ([]byte, error) {
+	c, err := NewSerpent(key, iv, args...)
+	if err != nil {
+		return nil, err
+	}
+	return c.Encrypt(plaintext)
+}
+
+func (cryptSerpent) Decrypt(ciphertext, key, iv []byte, args ...Options) ([]byte, error) {
+	c, err := NewSerpent(key, iv, args...)
+	if err != nil {
+		return nil, err
+	}
+	return c.Decrypt(ciphertext)
+}
@@ -0,0 +1,87 @@
+package crypt
+
+import (
+	"fmt"
+
+	ciphers "github.com/kayon/crypt/cipher"
+)
+
+// EncryptWithAADComponents is like EncryptWithAAD for MODE_SIV, which
+// authenticates each of aad as a separate component. The nonce, if the Crypt
+// has one, is authenticated as the last component. The same components must
+// be given to DecryptWithAADComponents in the same order.
+func (c Crypt) EncryptWithAADComponents(src []byte, aad ...[]byte) ([]byte, error) {
+	if c.method != METHOD_AES || c.mode != MODE_SIV {
+		return nil, fmt.Errorf("crypt %s.Encrypt: associated data components require MODE_SIV", c.method)
+	}
+	return sivEncrypt(src, c.key, c.iv, aad)
+}
+
+// DecryptWithAADComponents decrypts a ciphertext of EncryptWithAADComponents.
+// It returns an error wrapping ErrAuthentication if any component does not
+// match.
+func (c Crypt) DecryptWithAADComponents(src []byte, aad ...[]byte) ([]byte, error) {
+	if c.method != METHOD_AES || c.mode != MODE_SIV {
+		return nil, fmt.Errorf("crypt %s.Decrypt: associated data components require MODE_SIV", c.method)
+	}
+	return sivDecrypt(src, c.key, c.iv, aad)
+}
+
+// EncryptSIV deterministically encrypts plaintext with AES-SIV, see
+// Crypt.EncryptWithAADComponents. key is 32, 48 or 64 bytes.
+func (cryptAES) EncryptSIV(plaintext, key []byte, aad ...[]byte) ([]byte, error) {
+	c, err := NewAES(key, nil, Options{Mode: MODE_SIV})
+	if err != nil {
+		return nil, err
+	}
+	return c.EncryptWithAADComponents(plaintext, aad...)
+}
+
+// DecryptSIV decrypts a ciphertext of EncryptSIV.
+func (cryptAES) DecryptSIV(ciphertext, key []byte, aad ...[]byte) ([]byte, error) {
+	c, err := NewAES(key, nil, Options{Mode: MODE_SIV})
+	if err != nil {
+		return nil, err
+	}
+	return c.DecryptWithAADComponents(ciphertext, aad...)
+}
+
+// sivComponents returns aad as the only component, or none if it is nil.
+func sivComponents(aad []byte) [][]byte {
+	if aad == nil {
+		return nil
+	}
+	return [][]byte{aad}
+}
+
+func sivEncrypt(src, key, nonce []byte, aad [][]byte) ([]byte, error) {
+	siv, err := ciphers.NewSIV(key)
+	if err != nil {
+		return nil, err
+	}
+	if nonce != nil {
+		aad = append(aad[:len(aad):len(aad)], nonce)
+	}
+	if len(aad) > ciphers.SIVMaxComponents {
+		return nil, fmt.Errorf("crypt AES.Encrypt: too many SIV components, at most %d", ciphers.SIVMaxComponents)
+	}
+	return siv.Seal(nil, src, aad...)
+}
+
+func sivDecrypt(src, key, nonce []byte, aad [][]byte) ([]byte, error) {
+	siv, err := ciphers.NewSIV(key)
+	if err != nil {
+		return nil, err
+	}
+	if nonce != nil {
+		aad = append(aad[:len(aad):len(aad)], nonce)
+	}
+	if len(aad) > ciphers.SIVMaxComponents {
+		return nil, fmt.Errorf("crypt AES.Decrypt: too many SIV components, at most %d", ciphers.SIVMaxComponents)
+	}
+	plaintext, err := siv.Open(nil, src, aad...)
+	if err != nil {
+		return nil, fmt.Errorf("crypt AES.Decrypt: SIV %w", ErrAuthentication)
+	}
+	return plaintext, nil
+}
@@ -0,0 +1,114 @@
+package crypt
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestSIV(t *testing.T) {
+	var decode = func(s string) []byte {
+		b, err := hex.DecodeString(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return b
+	}
+	// RFC 5297 A.1, deterministic
+	key := decode("fffefdfcfbfaf9f8f7f6f5f4f3f2f1f0f0f1f2f3f4f5f6f7f8f9fafbfcfdfeff")
+	ad := decode("101112131415161718191a1b1c1d1e1f2021222324252627")
+	text := decode("112233445566778899aabbccddee")
+	want := "85632d07c6e8f37f950acd320a2ecc9340c02b9690c4dc04daef7f6afe5c"
+	ciphertext, err := AES.EncryptSIV(text, key, ad)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hex.EncodeToString(ciphertext) != want {
+		t.Fatalf("A.1: %x", ciphertext)
+	}
+	if ciphertext, err = AES.Encrypt(text, key, nil, Options{Mode: MODE_SIV, AAD: ad}); err != nil || hex.EncodeToString(ciphertext) != want {
+		t.Fatalf("A.1 Options.AAD: %x, %v", ciphertext, err)
+	}
+	if plaintext, err := AES.DecryptSIV(ciphertext, key, ad); err != nil || !bytes.Equal(plaintext, text) {
+		t.Fatalf("A.1 decrypt: %x, %v", plaintext, err)
+	}
+
+	// RFC 5297 A.2, with a nonce
+	key = decode("7f7e7d7c7b7a79787776757473727170404142434445464748494a4b4c4d4e4f")
+	ad1 := decode("00112233445566778899aabbccddeeffdeaddadadeaddadaffeeddccbbaa99887766554433221100")
+	ad2 := decode("102030405060708090a0")
+	nonce := decode("09f911029d74e35bd84156c5635688c0")
+	text = decode("7468697320697320736f6d6520706c61696e7465787420746f20656e6372797074207573696e67205349562d414553")
+	want = "7bdb6e3b432667eb06f4d14bff2fbd0fcb900f2fddbe4043266019" + "65c889bf17dba77ceb094fa663b7a3f748ba8af829ea64ad544a272e9c485b62a3fd5c0d"
+	c, err := NewAES(key, nonce, Options{Mode: MODE_SIV})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ciphertext, err = c.EncryptWithAADComponents(text, ad1, ad2); err != nil || hex.EncodeToString(ciphertext) != want {
+		t.Fatalf("A.2: %x, %v", ciphertext, err)
+	}
+	if plaintext, err := c.DecryptWithAADComponents(ciphertext, ad1, ad2); err != nil || !bytes.Equal(plaintext, text) {
+		t.Fatalf("A.2 decrypt: %x, %v", plaintext, err)
+	}
+	if _, err = c.DecryptWithAADComponents(ciphertext, ad2, ad1); !errors.Is(err, ErrAuthentication) {
+		t.Fatalf("components swapped: %v", err)
+	}
+	ciphertext[20] ^= 1
+	if _, err = c.DecryptWithAADComponents(ciphertext, ad1, ad2); !errors.Is(err, ErrAuthentication) {
+		t.Fatalf("modified ciphertext: %v", err)
+	}
+
+	for _, size := range []int{32, 48, 64} {
+		c, err := NewAES(randBytes(size), nil, Options{Mode: MODE_SIV})
+		if err != nil {
+			t.Fatal(size, err)
+		}
+		a, _ := c.Encrypt(text)
+		b, _ := c.Encrypt(text)
+		if !bytes.Equal(a, b) {
+			t.Fatalf("%d: not deterministic", size)
+		}
+		var buf bytes.Buffer
+		w, err := c.NewEncryptWriter(&buf)
+		if err != nil {
+			t.Fatal(size, err)
+		}
+		w.Write(text)
+		w.Close()
+		if !bytes.Equal(buf.Bytes(), a) {
+			t.Fatalf("%d: stream differs from Encrypt", size)
+		}
+		r, err := c.NewDecryptReader(&buf)
+		if err != nil {
+			t.Fatal(size, err)
+		}
+		if plaintext, err := io.ReadAll(r); err != nil || !bytes.Equal(plaintext, text) {
+			t.Fatalf("%d: stream %v", size, err)
+		}
+	}
+
+	var keySizeErr *KeySizeError
+	for _, size := range []int{16, 40, 72} {
+		if _, err = NewAES(randBytes(size), nil, Options{Mode: MODE_SIV}); !errors.As(err, &keySizeErr) {
+			t.Fatalf("%d byte key: %v", size, err)
+		}
+	}
+	if _, err = NewDES3(randBytes(24), nil, Options{Mode: MODE_SIV}); err == nil {
+		t.Fatal("DES3 accepted MODE_SIV")
+	}
+	envelope, err := c.EncryptEnvelope(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plaintext, err := Open(envelope, key); err != nil || !bytes.Equal(plaintext, text) {
+		t.Fatalf("envelope: %v", err)
+	}
+	if c, err = NewAES(randBytes(32), nil, Options{Mode: MODE_GCM}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = c.EncryptWithAADComponents(text, ad1, ad2); err == nil {
+		t.Fatal("GCM accepted associated data components")
+	}
+}
@@ -0,0 +1,23 @@
+package crypt
+
+const sm4SaltKeyByteSize = 16
+
+var SM4 cryptSM4
+
+type cryptSM4 struct{}
+
+func (cryptSM4) Encrypt(plaintext, key, iv []byte, args ...Options) ([]byte, error) {
+	c, err := NewSM4(key, iv, args...)
+	if err != nil {
+		return nil, err
+	}
+	return c.Encrypt(plaintext)
+}
+
+func (cryptSM4) Decrypt(ciphertext, key, iv []byte, args ...Options) ([]byte, error) {
+	c, err := NewSM4(key, iv, args...)
+	if err != nil {
+		return nil, err
+	}
+	return c.Decrypt(ciphertext)
+}
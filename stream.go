@@ -0,0 +1,460 @@
+package crypt
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"crypto/rc4"
+	"fmt"
+	"io"
+
+	ciphers "github.com/kayon/crypt/cipher"
+)
+
+const streamBufferSize = 32 * 1024
+
+// NewEncryptWriter returns a writer that encrypts everything written to it and
+// writes the ciphertext to w. The output is the same as Encrypt would produce
+// for the whole input. Close must be called to flush the final block, it does
+// not close w.
+//
+// The AEAD modes, MODE_CBC_CTS, the ChaCha20-Poly1305 methods and Options.MAC
+// buffer the whole message, use NewSealWriter to stream authenticated data.
+func (c Crypt) NewEncryptWriter(w io.Writer) (io.WriteCloser, error) {
+	if c.mac != MAC_NONE {
+		return &sealWriter{w: w, seal: func(plaintext []byte) ([]byte, error) {
+			return c.encryptThenMAC(plaintext, nil)
+		}}, nil
+	}
+	var key, iv, block = c.key, c.iv, c.block
+	var err error
+	if c.salted() {
+		var header []byte
+		if header, key, iv, err = genHeader(c.kdf, c.key, c.password, c.ivSize(), c.saltKeyByteSize()); err != nil {
+			return nil, err
+		}
+		if block != nil {
+			if block, err = newBlockCipher(c.method, key); err != nil {
+				return nil, err
+			}
+		}
+		if _, err = w.Write(header); err != nil {
+			return nil, err
+		}
+	}
+
+	if c.legacy {
+		return &blockWriter{w: w, bm: ciphers.NewECBEncrypter(block), pad: func(tail []byte) ([]byte, error) {
+			if len(tail) == 0 {
+				return nil, nil
+			}
+			return Padding(PAD_ZEROPADDING, tail, blowfishBlockSize)
+		}}, nil
+	}
+
+	switch c.method {
+	default:
+		if blockCipherOf(c.method) == nil {
+			return nil, fmt.Errorf("crypt.NewEncryptWriter unknown cipher method %d", c.method)
+		}
+		if err = c.checkIV(iv); err != nil {
+			return nil, err
+		}
+		pad := func(tail []byte) ([]byte, error) {
+			return Padding(c.padding, tail, block.BlockSize())
+		}
+		switch c.mode {
+		case MODE_CBC:
+			return &blockWriter{w: w, bm: cipher.NewCBCEncrypter(block, iv), pad: pad}, nil
+		case MODE_CFB:
+			return &streamWriter{w: w, s: cipher.NewCFBEncrypter(block, iv)}, nil
+		case MODE_CTR:
+			return &streamWriter{w: w, s: cipher.NewCTR(block, iv)}, nil
+		case MODE_OFB:
+			return &streamWriter{w: w, s: cipher.NewOFB(block, iv)}, nil
+		case MODE_ECB:
+			return &blockWriter{w: w, bm: ciphers.NewECBEncrypter(block), pad: pad}, nil
+		case MODE_IGE:
+			return &blockWriter{w: w, bm: ciphers.NewIGEEncrypter(block, iv), pad: pad}, nil
+		case MODE_PCBC:
+			return &blockWriter{w: w, bm: ciphers.NewPCBCEncrypter(block, iv), pad: pad}, nil
+		case MODE_GCM, MODE_CCM, MODE_EAX, MODE_OCB, MODE_GCMSIV:
+			aead, err := newAEAD(c.mode, key, block, len(iv), c.tagSize)
+			if err != nil {
+				return nil, err
+			}
+			return &sealWriter{w: w, seal: func(plaintext []byte) ([]byte, error) {
+				if uint64(len(plaintext)) > aeadMaxLength(c.mode, len(iv)) {
+					return nil, fmt.Errorf("crypt %s.NewEncryptWriter: plaintext too large for %s", c.method, c.mode)
+				}
+				return aead.Seal(nil, iv, plaintext, c.aad), nil
+			}}, nil
+		case MODE_SIV:
+			return &sealWriter{w: w, seal: func(plaintext []byte) ([]byte, error) {
+				return sivEncrypt(plaintext, key, iv, sivComponents(c.aad))
+			}}, nil
+		case MODE_XTS:
+			return &sealWriter{w: w, seal: func(plaintext []byte) ([]byte, error) {
+				return c.xts(plaintext, true)
+			}}, nil
+		case MODE_CBC_CTS:
+			return &sealWriter{w: w, seal: func(plaintext []byte) ([]byte, error) {
+				bm, err := newCTS(c.method, block, iv, c.cts, plaintext, true)
+				if err != nil {
+					return nil, err
+				}
+				var ciphertext = make([]byte, len(plaintext))
+				bm.CryptBlocks(ciphertext, plaintext)
+				return ciphertext, nil
+			}}, nil
+		}
+	case METHOD_CHACHA20:
+		stream, err := newChaCha20Stream(key, iv, c.counter)
+		if err != nil {
+			return nil, err
+		}
+		return &streamWriter{w: w, s: stream}, nil
+	case METHOD_SALSA20, METHOD_XSALSA20:
+		stream, err := newSalsa20Stream(c.method, key, iv)
+		if err != nil {
+			return nil, err
+		}
+		return &streamWriter{w: w, s: stream}, nil
+	case METHOD_CHACHA20POLY1305, METHOD_XCHACHA20POLY1305:
+		aead, err := newChaCha20Poly1305(c.method, key)
+		if err != nil {
+			return nil, err
+		}
+		return &sealWriter{w: w, seal: func(plaintext []byte) ([]byte, error) {
+			return aead.Seal(nil, iv, plaintext, c.aad), nil
+		}}, nil
+	case METHOD_RC4:
+		stream, err := rc4.NewCipher(key)
+		if err != nil {
+			return nil, err
+		}
+		return &streamWriter{w: w, s: stream}, nil
+	}
+	return nil, fmt.Errorf("crypt.NewEncryptWriter unknown cipher method %d", c.method)
+}
+
+// NewDecryptReader returns a reader that decrypts the ciphertext read from r.
+// A salted header at the start of r is read and used to derive the key and IV,
+// the same way Decrypt does.
+func (c Crypt) NewDecryptReader(r io.Reader) (io.Reader, error) {
+	if c.mac != MAC_NONE {
+		return &openReader{r: r, open: func(ciphertext []byte) ([]byte, error) {
+			return c.verifyThenDecrypt(ciphertext, nil)
+		}}, nil
+	}
+	var key, iv, block = c.key, c.iv, c.block
+	var err error
+	if c.saltKeyByteSize() > 0 && c.mode.Not(MODE_SIV, MODE_XTS) {
+		if key, iv, block, r, err = c.readHeader(r); err != nil {
+			return nil, err
+		}
+	}
+
+	if c.legacy {
+		return &blockReader{r: r, bm: ciphers.NewECBDecrypter(block), unpad: func(last []byte) ([]byte, error) {
+			return UnPadding(PAD_ZEROPADDING, last, blowfishBlockSize)
+		}}, nil
+	}
+
+	switch c.method {
+	default:
+		if blockCipherOf(c.method) == nil {
+			return nil, fmt.Errorf("crypt.NewDecryptReader unknown cipher method %d", c.method)
+		}
+		if err = c.checkIV(iv); err != nil {
+			return nil, err
+		}
+		unpad := func(last []byte) ([]byte, error) {
+			return UnPadding(c.padding, last, block.BlockSize())
+		}
+		switch c.mode {
+		case MODE_CBC:
+			return &blockReader{r: r, bm: cipher.NewCBCDecrypter(block, iv), unpad: unpad}, nil
+		case MODE_CFB:
+			return &cipher.StreamReader{S: cipher.NewCFBDecrypter(block, iv), R: r}, nil
+		case MODE_CTR:
+			return &cipher.StreamReader{S: cipher.NewCTR(block, iv), R: r}, nil
+		case MODE_OFB:
+			return &cipher.StreamReader{S: cipher.NewOFB(block, iv), R: r}, nil
+		case MODE_ECB:
+			return &blockReader{r: r, bm: ciphers.NewECBDecrypter(block), unpad: unpad}, nil
+		case MODE_IGE:
+			return &blockReader{r: r, bm: ciphers.NewIGEDecrypter(block, iv), unpad: unpad}, nil
+		case MODE_PCBC:
+			return &blockReader{r: r, bm: ciphers.NewPCBCDecrypter(block, iv), unpad: unpad}, nil
+		case MODE_GCM, MODE_CCM, MODE_EAX, MODE_OCB, MODE_GCMSIV:
+			aead, err := newAEAD(c.mode, key, block, len(iv), c.tagSize)
+			if err != nil {
+				return nil, err
+			}
+			return &openReader{r: r, open: func(ciphertext []byte) ([]byte, error) {
+				plaintext, err := aead.Open(nil, iv, ciphertext, c.aad)
+				if err != nil {
+					return nil, fmt.Errorf("crypt %s.NewDecryptReader: %s %w", c.method, c.mode, ErrAuthentication)
+				}
+				return plaintext, nil
+			}}, nil
+		case MODE_SIV:
+			return &openReader{r: r, open: func(ciphertext []byte) ([]byte, error) {
+				return sivDecrypt(ciphertext, key, iv, sivComponents(c.aad))
+			}}, nil
+		case MODE_XTS:
+			return &openReader{r: r, open: func(ciphertext []byte) ([]byte, error) {
+				return c.xts(ciphertext, false)
+			}}, nil
+		case MODE_CBC_CTS:
+			return &openReader{r: r, open: func(ciphertext []byte) ([]byte, error) {
+				bm, err := newCTS(c.method, block, iv, c.cts, ciphertext, false)
+				if err != nil {
+					return nil, err
+				}
+				var plaintext = make([]byte, len(ciphertext))
+				bm.CryptBlocks(plaintext, ciphertext)
+				return plaintext, nil
+			}}, nil
+		}
+	case METHOD_CHACHA20:
+		stream, err := newChaCha20Stream(key, iv, c.counter)
+		if err != nil {
+			return nil, err
+		}
+		return &cipher.StreamReader{S: stream, R: r}, nil
+	case METHOD_SALSA20, METHOD_XSALSA20:
+		stream, err := newSalsa20Stream(c.method, key, iv)
+		if err != nil {
+			return nil, err
+		}
+		return &cipher.StreamReader{S: stream, R: r}, nil
+	case METHOD_CHACHA20POLY1305, METHOD_XCHACHA20POLY1305:
+		if len(iv) != chacha20Poly1305NonceSize(c.method) {
+			return nil, nonceSizeError(c.method, len(iv), chacha20Poly1305NonceSize(c.method))
+		}
+		aead, err := newChaCha20Poly1305(c.method, key)
+		if err != nil {
+			return nil, err
+		}
+		return &openReader{r: r, open: func(ciphertext []byte) ([]byte, error) {
+			plaintext, err := aead.Open(nil, iv, ciphertext, c.aad)
+			if err != nil {
+				return nil, fmt.Errorf("crypt %s.NewDecryptReader: %w", c.method, ErrAuthentication)
+			}
+			return plaintext, nil
+		}}, nil
+	case METHOD_RC4:
+		stream, err := rc4.NewCipher(key)
+		if err != nil {
+			return nil, err
+		}
+		return &cipher.StreamReader{S: stream, R: r}, nil
+	}
+	return nil, fmt.Errorf("crypt.NewDecryptReader unknown cipher method %d", c.method)
+}
+
+// readHeader reads the header r starts with, if any, and returns the key, IV
+// and block cipher to decrypt the rest of the ciphertext with.
+func (c Crypt) readHeader(r io.Reader) (key, iv []byte, block cipher.Block, rest io.Reader, err error) {
+	var header []byte
+	if header, rest, err = readHeader(r); err != nil || header == nil {
+		return c.key, c.iv, c.block, rest, err
+	}
+	if _, key, iv, err = parseHeader(header, c.key, c.password, c.ivSize(), c.saltKeyByteSize()); err != nil {
+		return nil, nil, nil, nil, err
+	}
+	if c.block != nil {
+		if block, err = newBlockCipher(c.method, key); err != nil {
+			return nil, nil, nil, nil, err
+		}
+	}
+	return key, iv, block, rest, nil
+}
+
+// checkIV validates the IV of the block cipher methods before it is handed to
+// crypto/cipher, which would panic on a wrong length.
+func (c Crypt) checkIV(iv []byte) error {
+	if c.mode.Has(MODE_ECB, MODE_SIV) {
+		return nil
+	}
+	if len(iv) != c.ivSize() {
+		return nonceSizeError(c.method, len(iv), c.ivSize())
+	}
+	return nil
+}
+
+type streamWriter struct {
+	w io.Writer
+	s cipher.Stream
+}
+
+func (sw *streamWriter) Write(p []byte) (int, error) {
+	var out = make([]byte, len(p))
+	sw.s.XORKeyStream(out, p)
+	return sw.w.Write(out)
+}
+
+func (sw *streamWriter) Close() error {
+	return nil
+}
+
+// blockWriter encrypts full blocks as they are written and keeps the remainder
+// until Close, where it is padded.
+type blockWriter struct {
+	w      io.Writer
+	bm     cipher.BlockMode
+	pad    func(tail []byte) ([]byte, error)
+	buf    []byte
+	closed bool
+}
+
+func (bw *blockWriter) Write(p []byte) (int, error) {
+	if bw.closed {
+		return 0, fmt.Errorf("crypt: write to closed writer")
+	}
+	bw.buf = append(bw.buf, p...)
+	var n = len(bw.buf) - len(bw.buf)%bw.bm.BlockSize()
+	if n > 0 {
+		if err := bw.flush(bw.buf[:n]); err != nil {
+			return 0, err
+		}
+		bw.buf = append(bw.buf[:0], bw.buf[n:]...)
+	}
+	return len(p), nil
+}
+
+func (bw *blockWriter) Close() error {
+	if bw.closed {
+		return nil
+	}
+	bw.closed = true
+	final, err := bw.pad(bw.buf)
+	if err != nil {
+		return err
+	}
+	bw.buf = nil
+	if len(final)%bw.bm.BlockSize() != 0 {
+		return fmt.Errorf("crypt: input not full blocks")
+	}
+	return bw.flush(final)
+}
+
+func (bw *blockWriter) flush(plaintext []byte) error {
+	if len(plaintext) == 0 {
+		return nil
+	}
+	var out = make([]byte, len(plaintext))
+	bw.bm.CryptBlocks(out, plaintext)
+	_, err := bw.w.Write(out)
+	return err
+}
+
+// blockReader decrypts full blocks as they are read. The last two blocks are
+// held back until r reaches EOF so the padding can be removed, ISO/IEC 9797-1
+// padding may start in the second to last block.
+type blockReader struct {
+	r     io.Reader
+	bm    cipher.BlockMode
+	unpad func(last []byte) ([]byte, error)
+	in    []byte
+	out   []byte
+	err   error
+}
+
+func (br *blockReader) Read(p []byte) (int, error) {
+	for len(br.out) == 0 {
+		if br.err != nil {
+			return 0, br.err
+		}
+		br.fill()
+	}
+	n := copy(p, br.out)
+	br.out = br.out[n:]
+	return n, nil
+}
+
+func (br *blockReader) fill() {
+	var blockSize = br.bm.BlockSize()
+	var buf = make([]byte, streamBufferSize)
+	n, err := br.r.Read(buf)
+	br.in = append(br.in, buf[:n]...)
+	if err == io.EOF {
+		br.err = io.EOF
+		if len(br.in)%blockSize != 0 {
+			br.err = ErrInvalidPadding
+			return
+		}
+		if len(br.in) == 0 {
+			return
+		}
+		last := make([]byte, len(br.in))
+		br.bm.CryptBlocks(last, br.in)
+		br.in = nil
+		if br.out, err = br.unpad(last); err != nil {
+			br.out, br.err = nil, err
+		}
+		return
+	} else if err != nil {
+		br.err = err
+		return
+	}
+	if size := len(br.in) - len(br.in)%blockSize - 2*blockSize; size > 0 {
+		br.out = make([]byte, size)
+		br.bm.CryptBlocks(br.out, br.in[:size])
+		br.in = append(br.in[:0], br.in[size:]...)
+	}
+}
+
+// sealWriter buffers the whole plaintext for modes that can only encrypt a
+// complete message, such as GCM.
+type sealWriter struct {
+	w      io.Writer
+	seal   func(plaintext []byte) ([]byte, error)
+	buf    []byte
+	closed bool
+}
+
+func (sw *sealWriter) Write(p []byte) (int, error) {
+	if sw.closed {
+		return 0, fmt.Errorf("crypt: write to closed writer")
+	}
+	sw.buf = append(sw.buf, p...)
+	return len(p), nil
+}
+
+func (sw *sealWriter) Close() error {
+	if sw.closed {
+		return nil
+	}
+	sw.closed = true
+	ciphertext, err := sw.seal(sw.buf)
+	sw.buf = nil
+	if err != nil {
+		return err
+	}
+	_, err = sw.w.Write(ciphertext)
+	return err
+}
+
+// openReader reads the whole ciphertext before returning any plaintext.
+type openReader struct {
+	r    io.Reader
+	open func(ciphertext []byte) ([]byte, error)
+	out  *bytes.Reader
+	err  error
+}
+
+func (or *openReader) Read(p []byte) (int, error) {
+	if or.out == nil && or.err == nil {
+		var ciphertext, plaintext []byte
+		if ciphertext, or.err = io.ReadAll(or.r); or.err == nil {
+			plaintext, or.err = or.open(ciphertext)
+		}
+		or.out = bytes.NewReader(plaintext)
+	}
+	if or.err != nil {
+		return 0, or.err
+	}
+	return or.out.Read(p)
+}
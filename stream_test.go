@@ -0,0 +1,107 @@
+package crypt
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"testing/iotest"
+)
+
+func streamCrypts(t *testing.T) map[string]*Crypt {
+	var crypts = make(map[string]*Crypt)
+	var add = func(name string, c *Crypt, err error) {
+		if err != nil {
+			t.Fatal(name, err)
+		}
+		crypts[name] = c
+	}
+	for mode := MODE_CBC; mode <= MODE_ECB; mode++ {
+		for pad := PAD_PKCS7; pad <= PAD_NOPADDING; pad++ {
+			opts := Options{Mode: mode, Padding: pad}
+			name := mode.String() + "/" + pad.String()
+			c, err := NewAES([]byte(`15234c27ef5da06b`), nil, opts)
+			add("AES/"+name, c, err)
+			c, err = NewAES([]byte(`15234c27ef5da06b`), randBytes(map[bool]int{true: 12, false: 16}[mode == MODE_GCM]), opts)
+			add("AES/iv/"+name, c, err)
+			if mode.Has(MODE_GCM) {
+				continue
+			}
+			c, err = NewDES([]byte(`15234c27`), nil, opts)
+			add("DES/"+name, c, err)
+			c, err = NewDES3([]byte(`15234c2715234c2715234c27`), nil, opts)
+			add("DES3/"+name, c, err)
+		}
+	}
+	c, err := NewChaCha20(randBytes(32), nil)
+	add("ChaCha20", c, err)
+	c, err = NewChaCha20(randBytes(32), randBytes(12))
+	add("ChaCha20/iv", c, err)
+	c, err = NewChaCha20Poly1305(randBytes(32), nil, Options{AAD: []byte("aad")})
+	add("ChaCha20-Poly1305", c, err)
+	c, err = NewXChaCha20Poly1305(randBytes(32), randBytes(24))
+	add("XChaCha20-Poly1305/iv", c, err)
+	c, err = NewSalsa20(randBytes(32), randBytes(8))
+	add("Salsa20/iv", c, err)
+	c, err = NewXSalsa20(randBytes(32), nil)
+	add("XSalsa20", c, err)
+	c, err = NewBlowfish([]byte{1, 2, 3}, nil)
+	add("Blowfish", c, err)
+	c, err = NewBlowfish([]byte{1, 2, 3}, nil, Options{Legacy: true})
+	add("Blowfish/legacy", c, err)
+	c, err = NewRC4([]byte("123"))
+	add("RC4", c, err)
+	return crypts
+}
+
+func TestStream(t *testing.T) {
+	var sizes = []int{0, 5, 8, 16, 31, 32, streamBufferSize + 3}
+	for name, c := range streamCrypts(t) {
+		for _, size := range sizes {
+			text := randBytes(size)
+			if c.padding == PAD_NOPADDING && c.mode.Has(MODE_CBC, MODE_ECB) {
+				text = text[:size-size%c.block.BlockSize()]
+			} else if c.padding == PAD_ZEROPADDING {
+				// zero padding cannot restore trailing zeros
+				text = append(text, 1)
+			}
+
+			// streaming encrypt, buffered decrypt
+			var buf bytes.Buffer
+			w, err := c.NewEncryptWriter(&buf)
+			if err != nil {
+				t.Fatal(name, err)
+			}
+			for p := text; len(p) > 0; p = p[len(p)/2+1:] {
+				if _, err = w.Write(p[:len(p)/2+1]); err != nil {
+					t.Fatal(name, err)
+				}
+			}
+			if err = w.Close(); err != nil {
+				t.Fatal(name, err)
+			}
+			plaintext, err := c.Decrypt(buf.Bytes())
+			if err != nil {
+				t.Fatal(name, size, err)
+			}
+			if !bytes.Equal(plaintext, text) {
+				t.Fatalf("%s: stream encrypt wrong, size %d", name, size)
+			}
+
+			// buffered encrypt, streaming decrypt
+			ciphertext, err := c.Encrypt(text)
+			if err != nil {
+				t.Fatal(name, err)
+			}
+			r, err := c.NewDecryptReader(iotest.HalfReader(bytes.NewReader(ciphertext)))
+			if err != nil {
+				t.Fatal(name, err)
+			}
+			if plaintext, err = io.ReadAll(r); err != nil {
+				t.Fatal(name, size, err)
+			}
+			if !bytes.Equal(plaintext, text) {
+				t.Fatalf("%s: stream decrypt wrong, size %d", name, size)
+			}
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package crypt
+
+import (
+	"crypto/cipher"
+
+	"golang.org/x/crypto/twofish"
+)
+
+const twofishSaltKeyByteSize = 32
+
+var Twofish cryptTwofish
+
+type cryptTwofish struct{}
+
+func (cryptTwofish) Encrypt(plaintext, key, iv []byte, args ...Options) ([]byte, error) {
+	c, err := NewTwofish(key, iv, args...)
+	if err != nil {
+		return nil, err
+	}
+	return c.Encrypt(plaintext)
+}
+
+func (cryptTwofish) Decrypt(ciphertext, key, iv []byte, args ...Options) ([]byte, error) {
+	c, err := NewTwofish(key, iv, args...)
+	if err != nil {
+		return nil, err
+	}
+	return c.Decrypt(ciphertext)
+}
+
+func newTwofish(key []byte) (cipher.Block, error) {
+	return twofish.NewCipher(key)
+}
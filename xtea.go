@@ -0,0 +1,33 @@
+package crypt
+
+import (
+	"crypto/cipher"
+
+	"golang.org/x/crypto/xtea"
+)
+
+const xteaSaltKeyByteSize = 16
+
+var XTEA cryptXTEA
+
+type cryptXTEA struct{}
+
+func (cryptXTEA) Encrypt(plaintext, key, iv []byte, args ...Options) ([]byte, error) {
+	c, err := NewXTEA(key, iv, args...)
+	if err != nil {
+		return nil, err
+	}
+	return c.Encrypt(plaintext)
+}
+
+func (cryptXTEA) Decrypt(ciphertext, key, iv []byte, args ...Options) ([]byte, error) {
+	c, err := NewXTEA(key, iv, args...)
+	if err != nil {
+		return nil, err
+	}
+	return c.Decrypt(ciphertext)
+}
+
+func newXTEA(key []byte) (cipher.Block, error) {
+	return xtea.NewCipher(key)
+}
@@ -0,0 +1,61 @@
+package crypt
+
+import (
+	"fmt"
+
+	ciphers "github.com/kayon/crypt/cipher"
+)
+
+// EncryptSector encrypts the sector src, at least 16 bytes, into dst with the
+// sector number as the XTS tweak. dst must be at least as long as src and may
+// be src itself, the ciphertext has the same length. The Crypt must be in
+// MODE_XTS, its IV is not used.
+func (c Crypt) EncryptSector(dst, src []byte, sector uint64) error {
+	x, err := c.newXTS(dst, src)
+	if err != nil {
+		return err
+	}
+	x.Encrypt(dst, src, sector)
+	return nil
+}
+
+// DecryptSector decrypts a sector encrypted by EncryptSector.
+func (c Crypt) DecryptSector(dst, src []byte, sector uint64) error {
+	x, err := c.newXTS(dst, src)
+	if err != nil {
+		return err
+	}
+	x.Decrypt(dst, src, sector)
+	return nil
+}
+
+func (c Crypt) newXTS(dst, src []byte) (*ciphers.XTS, error) {
+	if c.method != METHOD_AES || c.mode != MODE_XTS {
+		return nil, fmt.Errorf("crypt %s: sectors require MODE_XTS", c.method)
+	}
+	if len(src) < c.block.BlockSize() {
+		return nil, fmt.Errorf("crypt AES: XTS data unit shorter than %d bytes", c.block.BlockSize())
+	}
+	if len(dst) < len(src) {
+		return nil, fmt.Errorf("crypt AES: output smaller than input")
+	}
+	return ciphers.NewXTS(c.block, c.tweak)
+}
+
+// xts encrypts or decrypts src as one data unit with the IV as the tweak.
+func (c Crypt) xts(src []byte, encrypt bool) ([]byte, error) {
+	if err := c.checkIV(c.iv); err != nil {
+		return nil, err
+	}
+	var dst = make([]byte, len(src))
+	x, err := c.newXTS(dst, src)
+	if err != nil {
+		return nil, err
+	}
+	if encrypt {
+		x.EncryptTweak(dst, src, c.iv)
+	} else {
+		x.DecryptTweak(dst, src, c.iv)
+	}
+	return dst, nil
+}
@@ -0,0 +1,90 @@
+package crypt
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"testing"
+)
+
+func TestXTS(t *testing.T) {
+	var vectors = []struct {
+		key, iv, plaintext, ciphertext string
+	}{
+		// IEEE 1619 vectors 2 and 15
+		{"1111111111111111111111111111111122222222222222222222222222222222", "33333333330000000000000000000000", "4444444444444444444444444444444444444444444444444444444444444444", "c454185e6a16936e39334038acef838bfb186fff7480adc4289382ecd6d394f0"},
+		{"fffefdfcfbfaf9f8f7f6f5f4f3f2f1f0bfbebdbcbbbab9b8b7b6b5b4b3b2b1b0", "9a785634120000000000000000000000", "000102030405060708090a0b0c0d0e0f10", "6c1625db4671522d3d7599601de7ca09ed"},
+		// made with pyca/cryptography on OpenSSL 3
+		{"000102030405060708090a0b0c0d0e0ff0e0d0c0b0a090807060504030201000102132435465768798a9bacbdcedfe0f102132435465768798a9bacbdcedfe0f", "0500000000000000aa00000000000000", "000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f202122232425262728292a2b2c", "a0b5b8b4712fc68e243c0643648e36ad56dea378c0b1b951a4b288d7c83d77b6c4ad9f6c0672df6e302333ebf8"},
+	}
+	for i, v := range vectors {
+		key, _ := hex.DecodeString(v.key)
+		iv, _ := hex.DecodeString(v.iv)
+		plaintext, _ := hex.DecodeString(v.plaintext)
+		ciphertext, err := AES.Encrypt(plaintext, key, iv, Options{Mode: MODE_XTS})
+		if err != nil {
+			t.Fatal(i, err)
+		}
+		if hex.EncodeToString(ciphertext) != v.ciphertext {
+			t.Fatalf("%d: Encrypt %x", i, ciphertext)
+		}
+		if decrypted, err := AES.Decrypt(ciphertext, key, iv, Options{Mode: MODE_XTS}); err != nil || !bytes.Equal(decrypted, plaintext) {
+			t.Fatalf("%d: Decrypt %x, %v", i, decrypted, err)
+		}
+	}
+
+	// vector 15 as a sector number, in place
+	key, _ := hex.DecodeString(vectors[1].key)
+	c, err := NewAES(key, nil, Options{Mode: MODE_XTS})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sector, _ := hex.DecodeString(vectors[1].plaintext)
+	if err = c.EncryptSector(sector, sector, 0x123456789a); err != nil {
+		t.Fatal(err)
+	}
+	if hex.EncodeToString(sector) != vectors[1].ciphertext {
+		t.Fatalf("EncryptSector %x", sector)
+	}
+	if err = c.DecryptSector(sector, sector, 0x123456789a); err != nil || hex.EncodeToString(sector) != vectors[1].plaintext {
+		t.Fatalf("DecryptSector %x, %v", sector, err)
+	}
+
+	for _, size := range []int{16, 31, 512, 4096 + 7} {
+		text := randBytes(size)
+		buf := make([]byte, size)
+		if err = c.EncryptSector(buf, text, 7); err != nil {
+			t.Fatal(size, err)
+		}
+		other := make([]byte, size)
+		c.EncryptSector(other, text, 8)
+		if bytes.Equal(buf, other) {
+			t.Fatalf("%d: sectors 7 and 8 encrypt the same", size)
+		}
+		if err = c.DecryptSector(buf, buf, 7); err != nil || !bytes.Equal(buf, text) {
+			t.Fatalf("%d: DecryptSector %v", size, err)
+		}
+	}
+
+	if err = c.EncryptSector(make([]byte, 15), make([]byte, 15), 0); err == nil {
+		t.Fatal("encrypted a partial block")
+	}
+	if _, err = c.Encrypt(make([]byte, 32)); err == nil {
+		t.Fatal("encrypted without a tweak")
+	}
+	if _, err = NewAES(make([]byte, 32), nil, Options{Mode: MODE_XTS}); err == nil {
+		t.Fatal("accepted equal key halves")
+	}
+	var keySizeErr *KeySizeError
+	for _, size := range []int{24, 48, 80} {
+		if _, err = NewAES(randBytes(size), nil, Options{Mode: MODE_XTS}); !errors.As(err, &keySizeErr) {
+			t.Fatalf("%d byte key: %v", size, err)
+		}
+	}
+	if c, err = NewAES(randBytes(32), nil, Options{Mode: MODE_GCM}); err != nil {
+		t.Fatal(err)
+	}
+	if err = c.EncryptSector(make([]byte, 16), make([]byte, 16), 0); err == nil {
+		t.Fatal("GCM encrypted a sector")
+	}
+}